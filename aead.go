@@ -0,0 +1,116 @@
+package trivium
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// NonceSize bytes of nonce for the AEAD, the nonce is the Trivium IV
+	NonceSize = KeyLength
+	// TagSize bytes of authentication tag appended by the AEAD, a full HMAC-SHA256
+	TagSize = sha256.Size
+)
+
+var errOpen = errors.New("trivium: message authentication failed")
+
+// aead is Trivium with encrypt-then-MAC using HMAC-SHA256, see NewAEAD.
+type aead struct {
+	key [KeyLength]byte
+}
+
+// NewAEAD returns a cipher.AEAD that encrypts with Trivium and authenticates with HMAC-SHA256.
+// The nonce is the 10-byte Trivium IV and must never be repeated with the same key.  For each
+// message the first 32 bytes of key stream are the HMAC key and the rest of the key stream
+// encrypts the plaintext.  The tag is the HMAC of the additional data and the ciphertext,
+// each followed by its length as a little-endian uint64 so the boundary between them is fixed.
+func NewAEAD(key [KeyLength]byte) cipher.AEAD {
+	return &aead{key: key}
+}
+
+// NonceSize returns the size of the nonce, the IV of Trivium.
+func (a *aead) NonceSize() int {
+	return NonceSize
+}
+
+// Overhead returns the size of the authentication tag.
+func (a *aead) Overhead() int {
+	return TagSize
+}
+
+// Seal encrypts and authenticates plaintext, authenticates additionalData and appends the
+// result to dst.
+func (a *aead) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != NonceSize {
+		panic("trivium: incorrect nonce length given to AEAD")
+	}
+	t, macKey := a.start(nonce)
+	ret, out := sliceForAppend(dst, len(plaintext)+TagSize)
+	ciphertext, tag := out[:len(plaintext)], out[len(plaintext):]
+	if inexactOverlap(out, plaintext) {
+		panic("trivium: invalid buffer overlap")
+	}
+	t.XORKeyStream(ciphertext, plaintext)
+	authenticate(tag[:0], macKey[:], additionalData, ciphertext)
+	return ret
+}
+
+// Open checks the tag and decrypts ciphertext, appending the plaintext to dst.  The ciphertext
+// is only decrypted once the tag has been checked in constant time.
+func (a *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("trivium: incorrect nonce length given to AEAD")
+	}
+	if len(ciphertext) < TagSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-TagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-TagSize]
+	t, macKey := a.start(nonce)
+	var expected [TagSize]byte
+	if !hmac.Equal(authenticate(expected[:0], macKey[:], additionalData, ciphertext), tag) {
+		return nil, errOpen
+	}
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	if inexactOverlap(out, ciphertext) {
+		panic("trivium: invalid buffer overlap")
+	}
+	t.XORKeyStream(out, ciphertext)
+	return ret, nil
+}
+
+// start initializes Trivium for nonce and takes the HMAC key from the start of the key stream.
+func (a *aead) start(nonce []byte) (*Trivium, [TagSize]byte) {
+	t := NewTrivium(a.key, [KeyLength]byte(nonce))
+	var macKey [TagSize]byte
+	t.KeyStream(macKey[:])
+	return t, macKey
+}
+
+// authenticate appends the HMAC-SHA256 of the additional data and ciphertext to dst.
+func authenticate(dst, macKey, additionalData, ciphertext []byte) []byte {
+	mac := hmac.New(sha256.New, macKey)
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData)))
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(ciphertext)))
+	mac.Write(additionalData)
+	mac.Write(lengths[:8])
+	mac.Write(ciphertext)
+	mac.Write(lengths[8:])
+	return mac.Sum(dst)
+}
+
+// sliceForAppend extends in by n bytes, returning the whole slice and the new bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
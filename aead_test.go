@@ -0,0 +1,131 @@
+package trivium
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+func TestAEAD(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var nonce = []byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	aead := NewAEAD(key)
+	if aead.NonceSize() != 10 || aead.Overhead() != 32 {
+		t.Errorf("NonceSize() = %d and Overhead() = %d, want 10 and 32", aead.NonceSize(), aead.Overhead())
+	}
+	for _, n := range []int{0, 1, 31, 64, 1000} {
+		plaintext := make([]byte, n)
+		for i := range plaintext {
+			plaintext[i] = byte(i)
+		}
+		ad := []byte("header")
+		prefix := []byte("prefix")
+		sealed := aead.Seal(prefix, nonce, plaintext, ad)
+		if !bytes.Equal(sealed[:len(prefix)], prefix) || len(sealed) != len(prefix)+n+TagSize {
+			t.Fatalf("Seal of %d bytes gave %d bytes", n, len(sealed))
+		}
+		ciphertext := sealed[len(prefix):]
+
+		// the ciphertext is the plaintext XORed with the key stream after the HMAC key
+		stream := make([]byte, TagSize+n)
+		NewTrivium(key, [KeyLength]byte(nonce)).KeyStream(stream)
+		for i := range plaintext {
+			if ciphertext[i] != plaintext[i]^stream[TagSize+i] {
+				t.Fatalf("ciphertext byte %d is not encrypted with the key stream after the HMAC key", i)
+			}
+		}
+
+		opened, err := aead.Open(nil, nonce, ciphertext, ad)
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Fatalf("Open of %d bytes = %X, %v", n, opened, err)
+		}
+		// in place
+		buf := append([]byte(nil), plaintext...)
+		buf = aead.Seal(buf[:0], nonce, buf, ad)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Errorf("in place Seal differs")
+		}
+		if buf, err = aead.Open(buf[:0], nonce, buf, ad); err != nil || !bytes.Equal(buf, plaintext) {
+			t.Errorf("in place Open = %X, %v", buf, err)
+		}
+	}
+}
+
+func TestAEADForgery(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var nonce = []byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	aead := NewAEAD(key)
+	ad := []byte("additional data")
+	ciphertext := aead.Seal(nil, nonce, []byte("attack at dawn"), ad)
+
+	// flipping any bit of the ciphertext, tag, additional data or nonce is rejected
+	for i := 0; i < len(ciphertext)*8; i++ {
+		forged := append([]byte(nil), ciphertext...)
+		forged[i/8] ^= 1 << (i % 8)
+		if _, err := aead.Open(nil, nonce, forged, ad); err == nil {
+			t.Fatalf("ciphertext with bit %d flipped was accepted", i)
+		}
+	}
+	for i := 0; i < len(ad)*8; i++ {
+		forged := append([]byte(nil), ad...)
+		forged[i/8] ^= 1 << (i % 8)
+		if _, err := aead.Open(nil, nonce, ciphertext, forged); err == nil {
+			t.Fatalf("additional data with bit %d flipped was accepted", i)
+		}
+	}
+	for i := 0; i < len(nonce)*8; i++ {
+		forged := append([]byte(nil), nonce...)
+		forged[i/8] ^= 1 << (i % 8)
+		if _, err := aead.Open(nil, forged, ciphertext, ad); err == nil {
+			t.Fatalf("nonce with bit %d flipped was accepted", i)
+		}
+	}
+	// moving bytes between the additional data and the ciphertext changes the tag
+	if _, err := aead.Open(nil, nonce, ciphertext[1:], append(ad, ciphertext[0])); err == nil {
+		t.Errorf("moving a byte from the ciphertext to the additional data was accepted")
+	}
+	if _, err := aead.Open(nil, nonce, ciphertext[:TagSize-1], ad); err == nil {
+		t.Errorf("a ciphertext shorter than the tag was accepted")
+	}
+	var wrongKey = key
+	wrongKey[0] ^= 1
+	if _, err := NewAEAD(wrongKey).Open(nil, nonce, ciphertext, ad); err == nil {
+		t.Errorf("the wrong key was accepted")
+	}
+}
+
+func TestAEADPanics(t *testing.T) {
+	var key [KeyLength]byte
+	aead := NewAEAD(key)
+	buf := make([]byte, 100)
+	cases := map[string]func(){
+		"short nonce Seal": func() { aead.Seal(nil, make([]byte, 9), nil, nil) },
+		"short nonce Open": func() { aead.Open(nil, make([]byte, 11), make([]byte, 40), nil) },
+		"overlapping Seal": func() { aead.Seal(buf[:1], make([]byte, 10), buf[:20], nil) },
+		"overlapping Open": func() {
+			aead.Open(buf[40:41], make([]byte, 10), aead.Seal(buf[40:40], make([]byte, 10), buf[:10], nil), nil)
+		},
+	}
+	for name, f := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func BenchmarkAEADSeal(b *testing.B) {
+	var key [KeyLength]byte
+	var aead cipher.AEAD = NewAEAD(key)
+	nonce := make([]byte, NonceSize)
+	buf := make([]byte, 1<<10, 1<<10+TagSize)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		aead.Seal(buf[:0], nonce, buf, nil)
+	}
+}
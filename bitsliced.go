@@ -0,0 +1,202 @@
+package trivium
+
+import "encoding/binary"
+
+const (
+	// Lanes is the number of independent Trivium instances run by a Bitsliced engine
+	Lanes = wordSize
+
+	// lengths of the three shift registers that make up the state
+	lenA = 93
+	lenB = 177 - 93
+	lenC = 288 - 177
+	// clocks of the state per block, one key stream bit per lane per clock
+	blockClocks = wordSize
+)
+
+// KeyIV is the key and initialization value (IV) of one instance of Trivium.
+type KeyIV struct {
+	Key, IV [KeyLength]byte
+}
+
+// Bitsliced runs up to Lanes independent instances of Trivium in lockstep.  The state is
+// bitsliced, each cell of the state is a uint64 holding that cell for every instance, one
+// instance per bit lane, so a single clock of the state costs the same few word operations
+// as NextBit but advances every instance at once.  This amortizes the 4*288 warm-up clocks
+// over many (key, IV) pairs, which dominates when encrypting many short messages.
+//
+// The three registers are held separately with the newest cell last.  A block of clocks
+// appends to each register and then slides the registers back to the start of the arrays.
+type Bitsliced struct {
+	a     [lenA + blockClocks]uint64 // a[lenA-i] is cell i of register A at the start of a block
+	b     [lenB + blockClocks]uint64 // b[lenB-i] is cell 93+i
+	c     [lenC + blockClocks]uint64 // c[lenC-i] is cell 177+i
+	lanes int
+
+	stream [Lanes]uint64 // key stream of each lane for the current block
+	used   int           // bytes of the current block already consumed
+}
+
+// NewBitsliced returns a Bitsliced engine with one lane initialized for each (key, IV) pair,
+// in the same way as NewTrivium.  It panics if there are more than Lanes pairs.
+func NewBitsliced(pairs []KeyIV) *Bitsliced {
+	var b Bitsliced
+	b.Reset(pairs)
+	return &b
+}
+
+// Reset re-initializes b in place with one lane for each (key, IV) pair, exactly as NewBitsliced
+// but without allocating.  It panics if there are more than Lanes pairs.
+func (b *Bitsliced) Reset(pairs []KeyIV) {
+	if len(pairs) > Lanes {
+		panic("trivium: too many key and IV pairs for a Bitsliced engine")
+	}
+	*b = Bitsliced{lanes: len(pairs)}
+	// transposing a row of key bits per lane gives a row of lanes per key bit
+	var low, high [Lanes]uint64
+	for lane, pair := range pairs {
+		low[lane] = binary.LittleEndian.Uint64(pair.Key[:])
+		high[lane] = uint64(binary.LittleEndian.Uint16(pair.Key[8:]))
+	}
+	transpose64(&low)
+	transpose64(&high)
+	for i := 0; i < wordSize; i++ {
+		b.a[lenA-1-i] = low[i]
+	}
+	for i := 0; i < KeyLength<<3-wordSize; i++ {
+		b.a[lenA-1-wordSize-i] = high[i]
+	}
+	for lane, pair := range pairs {
+		low[lane] = binary.LittleEndian.Uint64(pair.IV[:])
+		high[lane] = uint64(binary.LittleEndian.Uint16(pair.IV[8:]))
+	}
+	transpose64(&low)
+	transpose64(&high)
+	for i := 0; i < wordSize; i++ {
+		b.b[lenB-1-i] = low[i]
+	}
+	for i := 0; i < KeyLength<<3-wordSize; i++ {
+		b.b[lenB-1-wordSize-i] = high[i]
+	}
+	// cells 286, 287 and 288 are set in every lane
+	b.c[lenC-109], b.c[lenC-110], b.c[lenC-111] = ^uint64(0), ^uint64(0), ^uint64(0)
+
+	var z [blockClocks]uint64
+	for i := 0; i < 4*288/blockClocks; i++ {
+		b.block(&z)
+	}
+	b.used = wordSize >> 3 // no key stream has been generated yet
+}
+
+// Lanes returns the number of lanes in use, the number of (key, IV) pairs b was created with.
+func (b *Bitsliced) Lanes() int {
+	return b.lanes
+}
+
+// KeyStreams fills dst[i] with the key stream of lane i, the same bytes as the KeyStream of
+// NewTrivium with the i'th (key, IV) pair.  All lanes advance by the length of the longest dst.
+func (b *Bitsliced) KeyStreams(dst [][]byte) {
+	b.xorKeyStreams(dst, nil)
+}
+
+// XORKeyStreams XORs src[i] with the key stream of lane i and stores the result in dst[i].
+// All lanes advance in lockstep by the length of the longest src, the rest of the key stream
+// of lanes with a shorter src is discarded.
+func (b *Bitsliced) XORKeyStreams(dst, src [][]byte) {
+	if len(dst) < len(src) {
+		panic("trivium: fewer outputs than inputs")
+	}
+	for lane := range src {
+		if len(dst[lane]) < len(src[lane]) {
+			panic("trivium: output smaller than input")
+		}
+		if inexactOverlap(dst[lane][:len(src[lane])], src[lane]) {
+			panic("trivium: invalid buffer overlap")
+		}
+	}
+	b.xorKeyStreams(dst, src)
+}
+
+// xorKeyStreams XORs the key stream of each lane into dst, or stores it when src is nil.
+func (b *Bitsliced) xorKeyStreams(dst, src [][]byte) {
+	lanes := dst
+	if src != nil {
+		lanes = src
+	}
+	if len(lanes) > b.lanes {
+		panic("trivium: more buffers than lanes")
+	}
+	length := 0
+	for _, buf := range lanes {
+		if len(buf) > length {
+			length = len(buf)
+		}
+	}
+	var z [blockClocks]uint64
+	for offset := 0; offset < length; {
+		if b.used == wordSize>>3 {
+			b.block(&z)
+			transpose64(&z)
+			b.stream = z
+			b.used = 0
+		}
+		n := min(wordSize>>3-b.used, length-offset)
+		for lane, buf := range lanes {
+			word := b.stream[lane] >> (b.used << 3)
+			if n == wordSize>>3 && offset+n <= len(buf) { // a whole word of key stream
+				if src != nil {
+					word ^= binary.LittleEndian.Uint64(src[lane][offset:])
+				}
+				binary.LittleEndian.PutUint64(dst[lane][offset:], word)
+				continue
+			}
+			for i := offset; i < offset+n && i < len(buf); i++ {
+				if src != nil {
+					dst[lane][i] = src[lane][i] ^ byte(word)
+				} else {
+					dst[lane][i] = byte(word)
+				}
+				word >>= 8
+			}
+		}
+		b.used += n
+		offset += n
+	}
+}
+
+// block clocks every lane blockClocks times, storing the output of clock k in z[k].
+func (b *Bitsliced) block(z *[blockClocks]uint64) {
+	bitslicedBlock(&b.a, &b.b, &b.c, z)
+	copy(b.a[:lenA], b.a[blockClocks:])
+	copy(b.b[:lenB], b.b[blockClocks:])
+	copy(b.c[:lenC], b.c[blockClocks:])
+}
+
+// XORKeyStreams XORs each src[i] with the key stream for pairs[i] and stores the result in
+// dst[i], equivalent to NewTrivium(pairs[i].Key, pairs[i].IV).XORKeyStream(dst[i], src[i]).
+// The pairs are processed Lanes at a time with a Bitsliced engine.
+func XORKeyStreams(pairs []KeyIV, dst, src [][]byte) {
+	if len(src) != len(pairs) || len(dst) < len(src) {
+		panic("trivium: mismatched number of key and IV pairs and buffers")
+	}
+	var b Bitsliced
+	for len(pairs) > 0 {
+		n := min(len(pairs), Lanes)
+		b.Reset(pairs[:n])
+		b.XORKeyStreams(dst[:n], src[:n])
+		pairs, dst, src = pairs[n:], dst[n:], src[n:]
+	}
+}
+
+// transpose64 transposes the 64x64 bit matrix m in place, bit j of m[i] swaps with bit i of m[j].
+func transpose64(m *[64]uint64) {
+	mask := uint64(0x00000000FFFFFFFF)
+	for j := 32; j != 0; j >>= 1 {
+		for k := 0; k < 64; k = (k + j + 1) &^ j {
+			t := ((m[k] >> j) ^ m[k+j]) & mask
+			m[k] ^= t << j
+			m[k+j] ^= t
+		}
+		mask ^= mask << (j >> 1)
+	}
+}
@@ -0,0 +1,132 @@
+package trivium
+
+import (
+	"bytes"
+	"testing"
+)
+
+// testPairs returns n distinct key and IV pairs
+func testPairs(n int) []KeyIV {
+	pairs := make([]KeyIV, n)
+	x := uint32(0x12345678)
+	for i := range pairs {
+		for j := 0; j < KeyLength; j++ {
+			x ^= x << 13
+			x ^= x >> 17
+			x ^= x << 5
+			pairs[i].Key[j] = byte(x)
+			pairs[i].IV[j] = byte(x >> 8)
+		}
+	}
+	return pairs
+}
+
+func TestTranspose64(t *testing.T) {
+	var m, want [64]uint64
+	for i := range m {
+		m[i] = uint64(i)*0x9E3779B97F4A7C15 ^ uint64(i)<<7
+	}
+	for i := 0; i < 64; i++ {
+		for j := 0; j < 64; j++ {
+			want[j] |= (m[i] >> j & 1) << i
+		}
+	}
+	transpose64(&m)
+	if m != want {
+		t.Errorf("transpose64 doesn't match the bit by bit transpose")
+	}
+}
+
+func TestBitslicedLanes(t *testing.T) {
+	for _, lanes := range []int{1, 7, Lanes} {
+		pairs := testPairs(lanes)
+		bitsliced := NewBitsliced(pairs)
+		if bitsliced.Lanes() != lanes {
+			t.Errorf("Lanes() %d != %d", bitsliced.Lanes(), lanes)
+		}
+		want := make([][]byte, lanes)
+		for lane, pair := range pairs {
+			want[lane] = make([]byte, 300)
+			NewTrivium(pair.Key, pair.IV).KeyStream(want[lane])
+		}
+		// the key stream continues across calls that are not a multiple of the block size
+		offset := 0
+		for _, chunk := range []int{1, 3, 8, 13, 64, 0, 211} {
+			got := make([][]byte, lanes)
+			for lane := range got {
+				got[lane] = make([]byte, chunk)
+			}
+			bitsliced.KeyStreams(got)
+			for lane := range got {
+				if !bytes.Equal(got[lane], want[lane][offset:offset+chunk]) {
+					t.Errorf("%d lanes: lane %d doesn't match NewTrivium at offset %d", lanes, lane, offset)
+				}
+			}
+			offset += chunk
+		}
+	}
+}
+
+func TestXORKeyStreams(t *testing.T) {
+	pairs := testPairs(2*Lanes + 5)
+	src := make([][]byte, len(pairs))
+	dst := make([][]byte, len(pairs))
+	for i := range src {
+		src[i] = bytes.Repeat([]byte{byte(i)}, i%50) // lanes of different lengths
+		dst[i] = make([]byte, len(src[i]))
+	}
+	XORKeyStreams(pairs, dst, src)
+	for i, pair := range pairs {
+		want := make([]byte, len(src[i]))
+		NewTrivium(pair.Key, pair.IV).XORKeyStream(want, src[i])
+		if !bytes.Equal(dst[i], want) {
+			t.Errorf("pair %d doesn't match XORKeyStream of NewTrivium", i)
+		}
+	}
+}
+
+func TestBitslicedPanics(t *testing.T) {
+	pairs := testPairs(Lanes + 1)
+	for name, f := range map[string]func(){
+		"too many pairs": func() { NewBitsliced(pairs) },
+		"too many lanes": func() { NewBitsliced(pairs[:2]).KeyStreams(make([][]byte, 3)) },
+		"short dst":      func() { NewBitsliced(pairs[:1]).XORKeyStreams([][]byte{make([]byte, 1)}, [][]byte{make([]byte, 2)}) },
+		"mismatch":       func() { XORKeyStreams(pairs[:2], make([][]byte, 1), make([][]byte, 1)) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func BenchmarkPacketsTrivium(b *testing.B) {
+	pairs := testPairs(Lanes)
+	buf := make([]byte, 64)
+
+	b.SetBytes(int64(len(pairs) * len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, pair := range pairs {
+			NewTrivium(pair.Key, pair.IV).XORKeyStream(buf, buf)
+		}
+	}
+}
+
+func BenchmarkPacketsBitsliced(b *testing.B) {
+	pairs := testPairs(Lanes)
+	bufs := make([][]byte, len(pairs))
+	for i := range bufs {
+		bufs[i] = make([]byte, 64)
+	}
+
+	b.SetBytes(int64(len(pairs) * 64))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		XORKeyStreams(pairs, bufs, bufs)
+	}
+}
@@ -0,0 +1,176 @@
+package trivium
+
+import "encoding/binary"
+
+// Bivium is one of the reduced versions of Trivium with two registers introduced by Raddum
+// in "Cryptanalytic results on Trivium", the usual target of algebraic and SAT attacks.  The
+// 177-bit state is the first two registers of Trivium, cells 1 to 93 and 94 to 177, with the
+// same taps, except that the feedback of the second register replaces the third register:
+//
+//	t1 = s66 + s93
+//	t2 = s162 + s177
+//	z = t1 + t2 (Bivium-B) or z = t2 (Bivium-A)
+//	t1 = t1 + s91*s92 + s171
+//	t2 = t2 + s175*s176 + s69
+//	(s1, ..., s93) = (t2, s1, ..., s92)
+//	(s94, ..., s177) = (t1, s94, ..., s176)
+//
+// The key and IV are loaded as in Trivium and the warm-up is 4*177 cycles.  Bivium is for
+// cryptanalysis experiments only, it is not a secure cipher.
+type Bivium struct {
+	state [3]uint64
+	count uint64 // number of key stream bits produced since initialization
+	a     bool   // the output is t2 alone, Bivium-A
+}
+
+// NewBiviumA returns Bivium-A initialized with key and initialization value (IV), whose output
+// is only the second register, z = s162 + s177.
+func NewBiviumA(key, iv [KeyLength]byte) *Bivium {
+	b := Bivium{a: true}
+	b.Reset(key, iv)
+	return &b
+}
+
+// NewBiviumB returns Bivium-B initialized with key and initialization value (IV), whose output
+// is z = s66 + s93 + s162 + s177.
+func NewBiviumB(key, iv [KeyLength]byte) *Bivium {
+	var b Bivium
+	b.Reset(key, iv)
+	return &b
+}
+
+// Reset re-initializes b in place with key and initialization value (IV), keeping the variant.
+func (b *Bivium) Reset(key, iv [KeyLength]byte) {
+	// the key fills cells 1 to 80 and the IV cells 94 to 173 as in the first three words of Trivium
+	var t Trivium
+	t.load(key, iv)
+	copy(b.state[:], t.state[:])
+	b.Discard(4 * 177)
+	b.count = 0 // the warm-up is not part of the key stream
+}
+
+// NextBit gets the next bit from the Bivium stream.
+func (b *Bivium) NextBit() uint64 {
+	return b.NextBits(1)
+}
+
+// NextBits gets the next 1 to 64 bits from the Bivium stream.
+// The first bit produced is the least significant bit of the result.
+func (b *Bivium) NextBits(n uint) uint64 {
+	var bitmask = ^uint64(0) >> (wordSize - n)
+	s := &b.state
+	// get the taps, every tap is at least 64 cells from the start of its register
+	s66 := (s[i66] >> sh66) | (s[i66-1] << (wordSize - sh66))
+	s93 := (s[i93] >> sh93) | (s[i93-1] << (wordSize - sh93))
+	s162 := (s[i162] >> sh162) | (s[i162-1] << (wordSize - sh162))
+	s177 := (s[i177] >> sh177) | (s[i177-1] << (wordSize - sh177))
+
+	t1 := s66 ^ s93
+	t2 := s162 ^ s177
+	// store the output
+	z := t2 & bitmask
+	if !b.a {
+		z ^= t1 & bitmask
+	}
+	// process the taps
+	s91 := (s[i91] >> sh91) | (s[i91-1] << (wordSize - sh91))
+	s92 := (s[i92] >> sh92) | (s[i92-1] << (wordSize - sh92))
+	s171 := (s[i171] >> sh171) | (s[i171-1] << (wordSize - sh171))
+	s175 := (s[i175] >> sh175) | (s[i175-1] << (wordSize - sh175))
+	s176 := (s[i176] >> sh176) | (s[i176-1] << (wordSize - sh176))
+	s69 := (s[i69] >> sh69) | (s[i69-1] << (wordSize - sh69))
+
+	t1 ^= ((s91 & s92) ^ s171)
+	t2 ^= ((s175 & s176) ^ s69)
+	t1 &= bitmask
+	t2 &= bitmask
+
+	// rotate the state
+	s[2] = (s[2] >> n) | (s[1] << (wordSize - n))
+	s[1] = (s[1] >> n) | (s[0] << (wordSize - n))
+	s[0] = (s[0] >> n) | (t2 << (wordSize - n))
+	// update the final values
+	n94 := 92 + n
+	ni94 := n94 >> lgWordSize
+	nsh94 := mask - (n94 & mask)
+
+	s[ni94] = s[ni94] &^ (bitmask << nsh94)
+	s[ni94] |= t1 << nsh94
+	// need to handle overlap across word boundaries
+	s[i94] = s[i94] &^ (bitmask >> (wordSize - nsh94))
+	s[i94] |= t1 >> (wordSize - nsh94)
+
+	b.count += uint64(n)
+	return z
+}
+
+// NextUint64 gets the next 64 bits from the Bivium stream, the first bit produced is the
+// least significant bit.
+func (b *Bivium) NextUint64() uint64 {
+	return b.NextBits(wordSize)
+}
+
+// NextByte returns the next byte of key stream with the MSB as the last bit produced.
+func (b *Bivium) NextByte() byte {
+	return byte(b.NextBits(8))
+}
+
+// NextBytes returns the next 1 to 8 bytes of key stream with the MSB as the last bit produced.
+func (b *Bivium) NextBytes(n uint) []byte {
+	output := make([]byte, n)
+	word := b.NextBits(n << 3)
+	for i := uint(0); i < n; i++ {
+		output[i] = byte(word >> (i << 3))
+	}
+	return output
+}
+
+// KeyStream fills buf with the next len(buf) bytes of key stream, in the same order as
+// repeated calls to NextByte.
+func (b *Bivium) KeyStream(buf []byte) {
+	for ; len(buf) >= 8; buf = buf[8:] {
+		binary.LittleEndian.PutUint64(buf, b.NextUint64())
+	}
+	if len(buf) > 0 {
+		word := b.NextBits(uint(len(buf)) << 3)
+		for i := range buf {
+			buf[i] = byte(word >> (uint(i) << 3))
+		}
+	}
+}
+
+// XORKeyStream XORs each byte in src with a byte from the key stream and stores the result in dst.
+// dst and src must overlap entirely or not at all and len(dst) must be at least len(src).
+func (b *Bivium) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("trivium: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("trivium: invalid buffer overlap")
+	}
+	for ; len(src) >= 8; dst, src = dst[8:], src[8:] {
+		word := binary.LittleEndian.Uint64(src) ^ b.NextUint64()
+		binary.LittleEndian.PutUint64(dst, word)
+	}
+	if len(src) > 0 {
+		word := b.NextBits(uint(len(src)) << 3)
+		for i := range src {
+			dst[i] = src[i] ^ byte(word>>(uint(i)<<3))
+		}
+	}
+}
+
+// Discard advances the key stream by n bits without returning them.
+func (b *Bivium) Discard(n uint64) {
+	for ; n >= wordSize; n -= wordSize {
+		b.NextUint64()
+	}
+	if n > 0 {
+		b.NextBits(uint(n))
+	}
+}
+
+// Count returns the number of key stream bits produced since b was initialized.
+func (b *Bivium) Count() uint64 {
+	return b.count
+}
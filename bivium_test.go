@@ -0,0 +1,114 @@
+package trivium
+
+import (
+	"bytes"
+	"testing"
+)
+
+// biviumReference computes n bits of Bivium key stream one clock at a time, written directly
+// from the definition with one byte per cell and 1-based cell numbers.
+func biviumReference(key, iv [KeyLength]byte, n int, a bool) []byte {
+	bit := func(b []byte, i int) byte { return b[(i-1)>>3] >> ((i - 1) & 7) & 1 }
+	var s [178]byte
+	for i := 1; i <= 80; i++ {
+		s[i] = bit(key[:], i)
+		s[93+i] = bit(iv[:], i)
+	}
+	z := make([]byte, 0, n)
+	for i := 1; i <= 4*177+n; i++ {
+		t1 := s[66] ^ s[93]
+		t2 := s[162] ^ s[177]
+		if i > 4*177 {
+			if a {
+				z = append(z, t2)
+			} else {
+				z = append(z, t1^t2)
+			}
+		}
+		t1 ^= s[91]&s[92] ^ s[171]
+		t2 ^= s[175]&s[176] ^ s[69]
+		copy(s[2:94], s[1:93])
+		copy(s[95:178], s[94:177])
+		s[1], s[94] = t2, t1
+	}
+	return z
+}
+
+func TestBiviumReference(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	const bits = 1000
+	keys := [][2][KeyLength]byte{
+		{},
+		{key, iv},
+		{{0x01}, {}},
+		{{}, {9: 0x80}},
+	}
+	for _, keyIV := range keys {
+		for _, a := range []bool{true, false} {
+			want := biviumReference(keyIV[0], keyIV[1], bits, a)
+			b := NewBiviumB(keyIV[0], keyIV[1])
+			if a {
+				b = NewBiviumA(keyIV[0], keyIV[1])
+			}
+			for i, w := range want {
+				if got := b.NextBit(); got != uint64(w) {
+					t.Fatalf("Bivium-A %v key %X IV %X: bit %d is %d, want %d", a, keyIV[0], keyIV[1], i, got, w)
+				}
+			}
+		}
+	}
+}
+
+func TestBiviumBits(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	for _, newBivium := range []func(key, iv [KeyLength]byte) *Bivium{NewBiviumA, NewBiviumB} {
+		var stream [256]byte
+		newBivium(key, iv).KeyStream(stream[:])
+		// every width of NextBits must give the same key stream
+		for n := uint(1); n <= wordSize; n++ {
+			b := newBivium(key, iv)
+			for i := uint(0); i < uint(len(stream))<<3; i += n {
+				width := min(n, uint(len(stream))<<3-i)
+				got := b.NextBits(width)
+				for j := uint(0); j < width; j++ {
+					want := uint64(stream[(i+j)>>3]>>((i+j)&7)) & 1
+					if got>>j&1 != want {
+						t.Fatalf("NextBits(%d) bit %d is %d, want %d", n, i+j, got>>j&1, want)
+					}
+				}
+			}
+			if b.Count() != uint64(len(stream))<<3 {
+				t.Errorf("Count() = %d, want %d", b.Count(), len(stream)<<3)
+			}
+		}
+	}
+}
+
+func TestBiviumXORKeyStream(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	var stream [100]byte
+	NewBiviumB(key, iv).KeyStream(stream[:])
+	b := NewBiviumB(key, iv)
+	src := make([]byte, len(stream))
+	dst := make([]byte, len(stream))
+	for i := range src {
+		src[i] = byte(i)
+	}
+	b.XORKeyStream(dst[:13], src[:13])
+	b.XORKeyStream(dst[13:], src[13:])
+	for i := range dst {
+		if dst[i] != src[i]^stream[i] {
+			t.Fatalf("byte %d = %02X, want %02X", i, dst[i], src[i]^stream[i])
+		}
+	}
+	b.Reset(key, iv)
+	if got := b.NextBytes(8); !bytes.Equal(got, stream[:8]) {
+		t.Errorf("after Reset got %X, want %X", got, stream[:8])
+	}
+	if got := b.NextByte(); got != stream[8] {
+		t.Errorf("NextByte = %02X, want %02X", got, stream[8])
+	}
+}
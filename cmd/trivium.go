@@ -2,14 +2,19 @@ package main
 
 import (
 	"bufio"
+	"crypto/cipher"
 	"crypto/rand"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/bmkessler/trivium"
+	"github.com/bmkessler/trivium/testvectors"
 )
 
 // command line flags
@@ -18,18 +23,24 @@ const (
 	ENCRYPT = "e"
 	DECRYPT = "d"
 	GENKEY  = "g"
+	VECTORS = "v"
 )
 
 func main() {
 	var inputFile, outputFile, keyFile *os.File
-	var b byte
 
 	log.SetPrefix("trivium: ")
 
 	inputFileName := flag.String("i", DEFAULT, "input file, \"-\" reads from stdin")
 	outputFileName := flag.String("o", DEFAULT, "output file, \"-\" writes to stdout")
 	keyFileName := flag.String("k", DEFAULT, "key file, \"-\" writes to stdout")
-	mode := flag.String("m", DEFAULT, fmt.Sprintf("processing mode must be one of: %v=encrypt, %v=decrypt, %v=generate key", ENCRYPT, DECRYPT, GENKEY))
+	kekFileName := flag.String("kek", "", "key-encryption key file, if given the key file holds a key wrapped under it")
+	mode := flag.String("m", DEFAULT, fmt.Sprintf("processing mode must be one of: %v=encrypt, %v=decrypt, %v=generate key, %v=generate test vectors\n"+
+		"encryption is unauthenticated: a modified or truncated ciphertext decrypts to modified plaintext without any error", ENCRYPT, DECRYPT, GENKEY, VECTORS))
+	sets := flag.String("sets", "1,2,3,4,5,6", "test vectors: comma separated eSTREAM sets to generate")
+	pairsFileName := flag.String("pairs", "", "test vectors: file of \"key IV\" hex pairs, one per line, generated as set 1 instead of the eSTREAM sets")
+	convention := flag.String("convention", "estream", "test vectors: how keys and IVs are written, one of: raw, spec, estream")
+	order := flag.String("order", "lsb", "test vectors: key stream bit order, one of: lsb, msb")
 
 	flag.Parse()
 
@@ -43,12 +54,24 @@ func main() {
 		// read the key
 		keyreader := bufio.NewReader(keyFile)
 		keybuffer := make([]byte, trivium.KeyLength)
-		n, err := keyreader.Read(keybuffer)
-		if err != nil {
-			log.Fatalf("error reading key file %v: %v", keyFile.Name(), err)
-		}
-		if n != trivium.KeyLength {
-			log.Fatalf("Only read %d bytes < %d of input file %v for key", n, trivium.KeyLength, keyFile.Name())
+		if *kekFileName != "" { // the key is wrapped under the key-encryption key
+			wrapped, err := io.ReadAll(keyreader)
+			if err != nil {
+				log.Fatalf("error reading key file %v: %v", keyFile.Name(), err)
+			}
+			key, err := trivium.UnwrapTriviumKey(readKEK(*kekFileName), wrapped)
+			if err != nil {
+				log.Fatalf("error unwrapping key file %v: %v", keyFile.Name(), err)
+			}
+			copy(keybuffer, key[:])
+		} else {
+			n, err := keyreader.Read(keybuffer)
+			if err != nil {
+				log.Fatalf("error reading key file %v: %v", keyFile.Name(), err)
+			}
+			if n != trivium.KeyLength {
+				log.Fatalf("Only read %d bytes < %d of input file %v for key", n, trivium.KeyLength, keyFile.Name())
+			}
 		}
 		inputFile = openFile(*inputFileName)
 		defer inputFile.Close()
@@ -74,7 +97,7 @@ func main() {
 			key[i] = keybuffer[i]
 			iv[i] = ivbuffer[i]
 		}
-		triv := trivium.NewTrivium(key, iv)
+		triv := trivium.NewStream(key, iv)
 
 		outputFile = createFile(*outputFileName)
 		defer outputFile.Close()
@@ -89,15 +112,10 @@ func main() {
 				}
 			}
 		}
-		for b, err = reader.ReadByte(); err == nil; b, err = reader.ReadByte() {
-			kb := triv.NextByte()           // next byte of the keystream
-			err := writer.WriteByte(b ^ kb) // write the xor out
-			if err != nil {
-				log.Fatalf("error writing to %v: %v", outputFile.Name(), err)
-			}
-		}
-		if err != io.EOF {
-			log.Fatalf("error reading from %v: %v", inputFile.Name(), err)
+		// xor the input with the keystream on the way out, nothing authenticates the ciphertext
+		streamWriter := cipher.StreamWriter{S: triv, W: writer}
+		if _, err := io.Copy(streamWriter, reader); err != nil {
+			log.Fatalf("error processing %v to %v: %v", inputFile.Name(), outputFile.Name(), err)
 		}
 	case GENKEY:
 		keyFile = createFile(*keyFileName)
@@ -107,14 +125,34 @@ func main() {
 		if err != nil {
 			log.Fatalf("error generating %d random bytes for key: %v", trivium.KeyLength, err)
 		}
+		if *kekFileName != "" { // wrap the key under the key-encryption key
+			keybuffer = trivium.WrapKey(readKEK(*kekFileName), keybuffer)
+		}
 		n, err := keyFile.Write(keybuffer)
 		if err != nil {
 			log.Fatalf("error writing to %v: %v", keyFile.Name(), err)
 		}
-		if n != trivium.KeyLength {
+		if n != len(keybuffer) {
 			log.Fatalf("error only able to write %d bytes to %v", n, keyFile.Name())
 		}
 		log.Printf("wrote new key to %v", keyFile.Name())
+	case VECTORS:
+		vectors, err := selectVectors(*sets, *pairsFileName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		keyStream, err := keyStreamFunc(*convention, *order)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := vectors.Generate(keyStream); err != nil {
+			log.Fatal(err)
+		}
+		outputFile = createFile(*outputFileName)
+		defer outputFile.Close()
+		if err := vectors.Write(outputFile); err != nil {
+			log.Fatalf("error writing to %v: %v", outputFile.Name(), err)
+		}
 	default:
 		// no other modes are supported
 		flag.Usage()
@@ -151,3 +189,82 @@ func createFile(filename string) *os.File {
 	}
 	return file
 }
+
+// readKEK reads the raw 10-byte key-encryption key from a file and fatally logs on failure
+func readKEK(filename string) [trivium.KeyLength]byte {
+	kekFile := openFile(filename)
+	defer kekFile.Close()
+	var kek [trivium.KeyLength]byte
+	if _, err := io.ReadFull(kekFile, kek[:]); err != nil {
+		log.Fatalf("error reading key-encryption key file %v: %v", filename, err)
+	}
+	return kek
+}
+
+// selectVectors returns the chosen eSTREAM sets, or the key and IV pairs read from a file
+func selectVectors(sets, pairsFileName string) (*testvectors.File, error) {
+	vectors := testvectors.ESTREAMSets("TRIVIUM", "___H3", trivium.KeyLength<<3, trivium.KeyLength<<3)
+	if pairsFileName != "" {
+		pairsFile := openFile(pairsFileName)
+		defer pairsFile.Close()
+		vectors.Vectors, vectors.Notes = nil, nil
+		scanner := bufio.NewScanner(pairsFile)
+		for line := 1; scanner.Scan(); line++ {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%v line %d: want a key and an IV", pairsFileName, line)
+			}
+			key, err := hex.DecodeString(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%v line %d: key: %v", pairsFileName, line, err)
+			}
+			iv, err := hex.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%v line %d: IV: %v", pairsFileName, line, err)
+			}
+			vectors.Vectors = append(vectors.Vectors, testvectors.NewVector(1, len(vectors.Vectors), key, iv, testvectors.ShortStream))
+		}
+		return vectors, scanner.Err()
+	}
+	chosen := map[int]bool{}
+	for _, s := range strings.Split(sets, ",") {
+		set, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || set < 1 || set > 6 {
+			return nil, fmt.Errorf("invalid eSTREAM set %q, must be 1 to 6", s)
+		}
+		chosen[set] = true
+	}
+	all := vectors.Vectors
+	vectors.Vectors = nil
+	for _, v := range all {
+		if chosen[v.Set] {
+			vectors.Vectors = append(vectors.Vectors, v)
+		}
+	}
+	return vectors, nil
+}
+
+// keyStreamFunc returns the key stream of Trivium for keys and IVs written in a convention
+func keyStreamFunc(convention, order string) (testvectors.KeyStreamFunc, error) {
+	conventions := map[string]trivium.Convention{"raw": trivium.Raw, "spec": trivium.Spec, "estream": trivium.ESTREAM}
+	orders := map[string]trivium.BitOrder{"lsb": trivium.LSBFirst, "msb": trivium.MSBFirst}
+	c, ok := conventions[convention]
+	if !ok {
+		return nil, fmt.Errorf("unknown key convention %q", convention)
+	}
+	o, ok := orders[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown bit order %q", order)
+	}
+	return func(key, iv, stream []byte) error {
+		triv, err := trivium.NewTriviumWithOptions(key, iv, trivium.WithConvention(c), trivium.WithBitOrder(o))
+		if err != nil {
+			return err
+		}
+		triv.KeyStream(stream)
+		return nil
+	}, nil
+}
@@ -0,0 +1,34 @@
+package trivium
+
+import "encoding/binary"
+
+// The bulk key stream and the bitsliced block are the hot loops of the package.  On amd64 they
+// are implemented in assembly, see kernel_amd64.s, otherwise and with the purego build tag the
+// portable versions below are used.  The portable versions are always built so that the
+// assembly can be tested against them.
+
+// xorKeyStreamBlocksGeneric XORs whole words of src with the key stream into dst, len(src)
+// must be a multiple of 8.
+func xorKeyStreamBlocksGeneric(t *Trivium, dst, src []byte) {
+	for i := 0; i+8 <= len(src); i += 8 {
+		binary.LittleEndian.PutUint64(dst[i:], binary.LittleEndian.Uint64(src[i:])^t.NextUint64())
+	}
+}
+
+// bitslicedBlockGeneric clocks the registers of a Bitsliced engine blockClocks times, appending
+// the new cells of each register and storing the output of clock k in z[k].
+func bitslicedBlockGeneric(a *[lenA + blockClocks]uint64, b *[lenB + blockClocks]uint64, c *[lenC + blockClocks]uint64, z *[blockClocks]uint64) {
+	for k := 0; k < blockClocks; k++ {
+		// with the register ends at lenA+k, lenB+k and lenC+k cell i of A is a[k+lenA-i] etc.
+		t1 := a[k+lenA-66] ^ a[k]
+		t2 := b[k+lenB-69] ^ b[k]
+		t3 := c[k+lenC-66] ^ c[k]
+		z[k] = t1 ^ t2 ^ t3
+		t1 ^= (a[k+lenA-91] & a[k+lenA-92]) ^ b[k+lenB-78]
+		t2 ^= (b[k+lenB-82] & b[k+lenB-83]) ^ c[k+lenC-87]
+		t3 ^= (c[k+lenC-109] & c[k+lenC-110]) ^ a[k+lenA-69]
+		a[k+lenA] = t3
+		b[k+lenB] = t1
+		c[k+lenC] = t2
+	}
+}
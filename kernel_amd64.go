@@ -0,0 +1,65 @@
+//go:build amd64 && !purego
+
+package trivium
+
+// hasAVX2 selects the AVX2 bitsliced block, otherwise the SSE2 block that every amd64 CPU has is used.
+var hasAVX2 = detectAVX2()
+
+// xorKeyStreamBlocks XORs whole words of src with the key stream into dst, len(src) must be a
+// multiple of 8.
+func xorKeyStreamBlocks(t *Trivium, dst, src []byte) {
+	if words := len(src) >> 3; words > 0 {
+		xorKeyStreamAsm(&t.state, &dst[0], &src[0], words)
+		t.count += uint64(words) * wordSize
+	}
+}
+
+// bitslicedBlock clocks the registers of a Bitsliced engine blockClocks times.
+func bitslicedBlock(a *[lenA + blockClocks]uint64, b *[lenB + blockClocks]uint64, c *[lenC + blockClocks]uint64, z *[blockClocks]uint64) {
+	if hasAVX2 {
+		bitslicedBlockAVX2(&a[0], &b[0], &c[0], &z[0])
+	} else {
+		bitslicedBlockSSE2(&a[0], &b[0], &c[0], &z[0])
+	}
+}
+
+// detectAVX2 reports whether the CPU supports AVX2 and the OS saves the YMM registers.
+func detectAVX2() bool {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	const osxsave, avx = 1 << 27, 1 << 28
+	if ecx1&osxsave == 0 || ecx1&avx == 0 {
+		return false
+	}
+	// the OS must save the XMM (bit 1) and YMM (bit 2) state
+	if xcr0, _ := xgetbv(); xcr0&6 != 6 {
+		return false
+	}
+	_, ebx7, _, _ := cpuid(7, 0)
+	const avx2 = 1 << 5
+	return ebx7&avx2 != 0
+}
+
+// xorKeyStreamAsm XORs words uint64s at src with the key stream into dst, advancing state.
+//
+//go:noescape
+func xorKeyStreamAsm(state *[5]uint64, dst, src *byte, words int)
+
+// bitslicedBlockAVX2 is bitslicedBlockGeneric computing 4 clocks per step.
+//
+//go:noescape
+func bitslicedBlockAVX2(a, b, c, z *uint64)
+
+// bitslicedBlockSSE2 is bitslicedBlockGeneric computing 2 clocks per step.
+//
+//go:noescape
+func bitslicedBlockSSE2(a, b, c, z *uint64)
+
+// cpuid executes the CPUID instruction with the given EAX and ECX.
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+// xgetbv returns the low and high halves of extended control register 0.
+func xgetbv() (eax, edx uint32)
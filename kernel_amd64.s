@@ -0,0 +1,240 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func xorKeyStreamAsm(state *[5]uint64, dst, src *byte, words int)
+//
+// The state is held in R8 to R12 for the whole loop.  Each tap is a funnel shift of two state
+// words, SHRQ $n, hi, lo computes lo = lo>>n | hi<<(64-n), which is the tap computation of
+// NextUint64 in a single instruction.
+TEXT ·xorKeyStreamAsm(SB), NOSPLIT, $0-32
+	MOVQ state+0(FP), AX
+	MOVQ dst+8(FP), DI
+	MOVQ src+16(FP), SI
+	MOVQ words+24(FP), CX
+	MOVQ 0(AX), R8
+	MOVQ 8(AX), R9
+	MOVQ 16(AX), R10
+	MOVQ 24(AX), R11
+	MOVQ 32(AX), R12
+	// index from -8*words up to zero
+	SHLQ $3, CX
+	ADDQ CX, DI
+	ADDQ CX, SI
+	NEGQ CX
+
+loop:
+	// t1 = s66 ^ s93 in BX
+	MOVQ R9, BX
+	SHRQ $62, R8, BX
+	MOVQ R9, DX
+	SHRQ $35, R8, DX
+	XORQ DX, BX
+	// t2 = s162 ^ s177 in R13
+	MOVQ R10, R13
+	SHRQ $30, R9, R13
+	MOVQ R10, DX
+	SHRQ $15, R9, DX
+	XORQ DX, R13
+	// t3 = s243 ^ s288 in R14
+	MOVQ R11, R14
+	SHRQ $13, R10, R14
+	MOVQ R12, DX
+	SHRQ $32, R11, DX
+	XORQ DX, R14
+	// output z = t1 ^ t2 ^ t3 xor the source
+	MOVQ (SI)(CX*1), DX
+	XORQ BX, DX
+	XORQ R13, DX
+	XORQ R14, DX
+	MOVQ DX, (DI)(CX*1)
+	// t1 ^= (s91 & s92) ^ s171
+	MOVQ R9, DX
+	SHRQ $37, R8, DX
+	MOVQ R9, AX
+	SHRQ $36, R8, AX
+	ANDQ AX, DX
+	MOVQ R10, AX
+	SHRQ $21, R9, AX
+	XORQ AX, DX
+	XORQ DX, BX
+	// t2 ^= (s175 & s176) ^ s264
+	MOVQ R10, DX
+	SHRQ $17, R9, DX
+	MOVQ R10, AX
+	SHRQ $16, R9, AX
+	ANDQ AX, DX
+	MOVQ R12, AX
+	SHRQ $56, R11, AX
+	XORQ AX, DX
+	XORQ DX, R13
+	// t3 ^= (s286 & s287) ^ s69
+	MOVQ R12, DX
+	SHRQ $34, R11, DX
+	MOVQ R12, AX
+	SHRQ $33, R11, AX
+	ANDQ AX, DX
+	MOVQ R9, AX
+	SHRQ $59, R8, AX
+	XORQ AX, DX
+	XORQ DX, R14
+	// rotate the state by a whole word
+	MOVQ R11, R12
+	MOVQ R10, R11
+	MOVQ R9, R10
+	MOVQ R8, R9
+	MOVQ R14, R8
+	// t1 fills cells 94 to 157, the low 35 bits of word 1 and the high 29 bits of word 2
+	SHRQ $35, R9
+	SHLQ $35, BX, R9
+	SHLQ $29, R10
+	SHRQ $29, BX, R10
+	// t2 fills cells 178 to 241, the low 15 bits of word 2 and the high 49 bits of word 3
+	SHRQ $15, R10
+	SHLQ $15, R13, R10
+	SHLQ $49, R11
+	SHRQ $49, R13, R11
+
+	ADDQ $8, CX
+	JNZ  loop
+
+	MOVQ state+0(FP), AX
+	MOVQ R8, 0(AX)
+	MOVQ R9, 8(AX)
+	MOVQ R10, 16(AX)
+	MOVQ R11, 24(AX)
+	MOVQ R12, 32(AX)
+	RET
+
+// The bitsliced blocks compute consecutive clocks in the lanes of a vector register.  Every tap
+// is at least 66 cells from the end of its register, so the clocks in a vector never depend on
+// each other.  The byte offsets of the taps are 8*(register length - cell) from the start of the
+// block, for example cell 66 of register A is at 8*(93-66) = 216.
+
+// func bitslicedBlockAVX2(a, b, c, z *uint64)
+TEXT ·bitslicedBlockAVX2(SB), NOSPLIT, $0-32
+	MOVQ a+0(FP), AX
+	MOVQ b+8(FP), BX
+	MOVQ c+16(FP), CX
+	MOVQ z+24(FP), DX
+	MOVQ $16, SI
+
+avx2Loop:
+	// t1 = A66 ^ A93, t2 = B69 ^ B84, t3 = C66 ^ C111
+	VMOVDQU 216(AX), Y0
+	VPXOR   0(AX), Y0, Y0
+	VMOVDQU 120(BX), Y1
+	VPXOR   0(BX), Y1, Y1
+	VMOVDQU 360(CX), Y2
+	VPXOR   0(CX), Y2, Y2
+	// z = t1 ^ t2 ^ t3
+	VPXOR   Y0, Y1, Y3
+	VPXOR   Y2, Y3, Y3
+	VMOVDQU Y3, 0(DX)
+	// t1 ^= (A91 & A92) ^ B78
+	VMOVDQU 16(AX), Y4
+	VPAND   8(AX), Y4, Y4
+	VPXOR   48(BX), Y4, Y4
+	VPXOR   Y4, Y0, Y0
+	// t2 ^= (B82 & B83) ^ C87
+	VMOVDQU 16(BX), Y4
+	VPAND   8(BX), Y4, Y4
+	VPXOR   192(CX), Y4, Y4
+	VPXOR   Y4, Y1, Y1
+	// t3 ^= (C109 & C110) ^ A69
+	VMOVDQU 16(CX), Y4
+	VPAND   8(CX), Y4, Y4
+	VPXOR   192(AX), Y4, Y4
+	VPXOR   Y4, Y2, Y2
+	// append t3 to A, t1 to B and t2 to C
+	VMOVDQU Y2, 744(AX)
+	VMOVDQU Y0, 672(BX)
+	VMOVDQU Y1, 888(CX)
+
+	ADDQ $32, AX
+	ADDQ $32, BX
+	ADDQ $32, CX
+	ADDQ $32, DX
+	DECQ SI
+	JNZ  avx2Loop
+
+	VZEROUPPER
+	RET
+
+// func bitslicedBlockSSE2(a, b, c, z *uint64)
+TEXT ·bitslicedBlockSSE2(SB), NOSPLIT, $0-32
+	MOVQ a+0(FP), AX
+	MOVQ b+8(FP), BX
+	MOVQ c+16(FP), CX
+	MOVQ z+24(FP), DX
+	MOVQ $32, SI
+
+sse2Loop:
+	// t1 = A66 ^ A93, t2 = B69 ^ B84, t3 = C66 ^ C111
+	MOVOU 216(AX), X0
+	MOVOU 0(AX), X5
+	PXOR  X5, X0
+	MOVOU 120(BX), X1
+	MOVOU 0(BX), X5
+	PXOR  X5, X1
+	MOVOU 360(CX), X2
+	MOVOU 0(CX), X5
+	PXOR  X5, X2
+	// z = t1 ^ t2 ^ t3
+	MOVO  X0, X3
+	PXOR  X1, X3
+	PXOR  X2, X3
+	MOVOU X3, 0(DX)
+	// t1 ^= (A91 & A92) ^ B78
+	MOVOU 16(AX), X4
+	MOVOU 8(AX), X5
+	PAND  X5, X4
+	MOVOU 48(BX), X5
+	PXOR  X5, X4
+	PXOR  X4, X0
+	// t2 ^= (B82 & B83) ^ C87
+	MOVOU 16(BX), X4
+	MOVOU 8(BX), X5
+	PAND  X5, X4
+	MOVOU 192(CX), X5
+	PXOR  X5, X4
+	PXOR  X4, X1
+	// t3 ^= (C109 & C110) ^ A69
+	MOVOU 16(CX), X4
+	MOVOU 8(CX), X5
+	PAND  X5, X4
+	MOVOU 192(AX), X5
+	PXOR  X5, X4
+	PXOR  X4, X2
+	// append t3 to A, t1 to B and t2 to C
+	MOVOU X2, 744(AX)
+	MOVOU X0, 672(BX)
+	MOVOU X1, 888(CX)
+
+	ADDQ $16, AX
+	ADDQ $16, BX
+	ADDQ $16, CX
+	ADDQ $16, DX
+	DECQ SI
+	JNZ  sse2Loop
+
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
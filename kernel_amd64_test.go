@@ -0,0 +1,15 @@
+//go:build amd64 && !purego
+
+package trivium
+
+import "testing"
+
+func TestBitslicedBlockSSE2(t *testing.T) {
+	if !hasAVX2 {
+		t.Skip("the SSE2 block is already tested by TestBitslicedBlock without AVX2")
+	}
+	hasAVX2 = false
+	defer func() { hasAVX2 = true }()
+	TestBitslicedBlock(t)
+	TestBitslicedLanes(t)
+}
@@ -0,0 +1,14 @@
+//go:build !amd64 || purego
+
+package trivium
+
+// xorKeyStreamBlocks XORs whole words of src with the key stream into dst, len(src) must be a
+// multiple of 8.
+func xorKeyStreamBlocks(t *Trivium, dst, src []byte) {
+	xorKeyStreamBlocksGeneric(t, dst, src)
+}
+
+// bitslicedBlock clocks the registers of a Bitsliced engine blockClocks times.
+func bitslicedBlock(a *[lenA + blockClocks]uint64, b *[lenB + blockClocks]uint64, c *[lenC + blockClocks]uint64, z *[blockClocks]uint64) {
+	bitslicedBlockGeneric(a, b, c, z)
+}
@@ -0,0 +1,74 @@
+package trivium
+
+import (
+	"bytes"
+	"testing"
+)
+
+// randomState returns a Trivium with a state that is not reachable from any key and IV, to
+// exercise every cell of the kernels.
+func randomState(seed uint64) *Trivium {
+	var t Trivium
+	for i := range t.state {
+		seed = seed*6364136223846793005 + 1442695040888963407
+		t.state[i] = seed ^ seed>>29
+	}
+	return &t
+}
+
+func TestXORKeyStreamBlocks(t *testing.T) {
+	for seed := uint64(0); seed < 20; seed++ {
+		src := make([]byte, 8*int(seed+1)*7)
+		for i := range src {
+			src[i] = byte(i) ^ byte(seed)
+		}
+		want := make([]byte, len(src))
+		got := make([]byte, len(src))
+		generic, kernel := randomState(seed), randomState(seed)
+		xorKeyStreamBlocksGeneric(generic, want, src)
+		xorKeyStreamBlocks(kernel, got, src)
+		if !bytes.Equal(got, want) {
+			t.Errorf("seed %d: xorKeyStreamBlocks output doesn't match the generic version", seed)
+		}
+		if kernel.state != generic.state || kernel.count != generic.count {
+			t.Errorf("seed %d: xorKeyStreamBlocks final state doesn't match the generic version", seed)
+		}
+	}
+}
+
+func TestBitslicedBlock(t *testing.T) {
+	var (
+		a, wantA [lenA + blockClocks]uint64
+		b, wantB [lenB + blockClocks]uint64
+		c, wantC [lenC + blockClocks]uint64
+		z, wantZ [blockClocks]uint64
+	)
+	seed := uint64(1)
+	for _, reg := range [][]uint64{a[:], b[:], c[:]} {
+		for i := range reg {
+			seed = seed*6364136223846793005 + 1442695040888963407
+			reg[i] = seed ^ seed>>29
+		}
+	}
+	wantA, wantB, wantC = a, b, c
+	bitslicedBlockGeneric(&wantA, &wantB, &wantC, &wantZ)
+	bitslicedBlock(&a, &b, &c, &z)
+	if a != wantA || b != wantB || c != wantC || z != wantZ {
+		t.Errorf("bitslicedBlock doesn't match the generic version")
+	}
+}
+
+func BenchmarkBitslicedBlock(b *testing.B) {
+	var (
+		a  [lenA + blockClocks]uint64
+		bb [lenB + blockClocks]uint64
+		c  [lenC + blockClocks]uint64
+		z  [blockClocks]uint64
+	)
+
+	b.SetBytes(Lanes * blockClocks >> 3)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bitslicedBlock(&a, &bb, &c, &z)
+	}
+}
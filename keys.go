@@ -0,0 +1,229 @@
+package trivium
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Key is an 80-bit Trivium key in the byte order used by NewTrivium, see Raw.
+type Key [KeyLength]byte
+
+// IV is an 80-bit Trivium initialization value in the byte order used by NewTrivium, see Raw.
+type IV [KeyLength]byte
+
+// Convention is a way of writing the 80 bits of a key or IV as 10 bytes.  The specification
+// numbers the bits of the key K1 to K80 and loads them into cells 1 to 80 of the state, the
+// IV bits IV1 to IV80 are loaded into cells 94 to 173.  Below bit i is K(i+1) or IV(i+1).
+//
+// Every convention loads the same state, they differ only in how the bytes are written, so
+// a key and IV can be moved between them with Convert and give the same key stream.  The key
+// stream is always packed with the first bit in the least significant bit of each byte, as
+// in the eSTREAM test vectors, see WithBitOrder for the other order.
+type Convention int
+
+const (
+	// Raw is the byte order of NewTrivium, bit i is bit i%8 of byte i/8 counting from the
+	// least significant bit, so the first byte holds K8 ... K1 from left to right.
+	Raw Convention = iota
+	// Spec writes the bits in the order of the specification, bit i is bit 7-i%8 of byte i/8,
+	// so the hex reads K1 K2 ... K80 from left to right.
+	Spec
+	// ESTREAM is the order of the eSTREAM test vectors, bit i is bit 7-i%8 of byte 9-i/8.
+	// Compared to Raw the byte order and the bits of every byte are reversed.
+	ESTREAM
+)
+
+var errKeyHexLength = fmt.Errorf("trivium: hex key or IV must be %d digits", 2*KeyLength)
+
+// String returns the name of the convention.
+func (c Convention) String() string {
+	switch c {
+	case Raw:
+		return "Raw"
+	case Spec:
+		return "Spec"
+	case ESTREAM:
+		return "ESTREAM"
+	}
+	return fmt.Sprintf("Convention(%d)", int(c))
+}
+
+// ParseKey parses a key written as 20 hex digits in convention c.
+func ParseKey(s string, c Convention) (Key, error) {
+	b, err := parseHex(s, c)
+	return Key(b), err
+}
+
+// ParseIV parses an IV written as 20 hex digits in convention c.
+func ParseIV(s string, c Convention) (IV, error) {
+	b, err := parseHex(s, c)
+	return IV(b), err
+}
+
+// KeyFromBytes returns the key written as 10 bytes in convention c.
+func KeyFromBytes(b []byte, c Convention) (Key, error) {
+	k, err := fromBytes(b, c)
+	return Key(k), err
+}
+
+// IVFromBytes returns the IV written as 10 bytes in convention c.
+func IVFromBytes(b []byte, c Convention) (IV, error) {
+	iv, err := fromBytes(b, c)
+	return IV(iv), err
+}
+
+// Bytes returns the key written as 10 bytes in convention c, it panics for an unknown convention.
+func (k Key) Bytes(c Convention) []byte {
+	b := reorder(k, c)
+	return b[:]
+}
+
+// Format returns the key written as 20 upper case hex digits in convention c.
+func (k Key) Format(c Convention) string {
+	return strings.ToUpper(hex.EncodeToString(k.Bytes(c)))
+}
+
+// Bytes returns the IV written as 10 bytes in convention c, it panics for an unknown convention.
+func (iv IV) Bytes(c Convention) []byte {
+	b := reorder(iv, c)
+	return b[:]
+}
+
+// Format returns the IV written as 20 upper case hex digits in convention c.
+func (iv IV) Format(c Convention) string {
+	return strings.ToUpper(hex.EncodeToString(iv.Bytes(c)))
+}
+
+// Convert rewrites 10 bytes of a key or IV from convention from to convention to.
+func Convert(b []byte, from, to Convention) ([]byte, error) {
+	raw, err := fromBytes(b, from)
+	if err != nil {
+		return nil, err
+	}
+	if to < Raw || to > ESTREAM {
+		return nil, errors.New("trivium: unknown key convention " + to.String())
+	}
+	out := reorder(raw, to)
+	return out[:], nil
+}
+
+// NewTriviumESTREAM returns a Trivium cipher for a key and IV written as hex in the order of
+// the eSTREAM test vectors.
+func NewTriviumESTREAM(key, iv string) (*Trivium, error) {
+	return newTriviumHex(key, iv, ESTREAM)
+}
+
+// NewTriviumSpec returns a Trivium cipher for a key and IV written as hex in the bit order of
+// the specification, K1 to K80 and IV1 to IV80 from left to right.
+func NewTriviumSpec(key, iv string) (*Trivium, error) {
+	return newTriviumHex(key, iv, Spec)
+}
+
+func newTriviumHex(keyHex, ivHex string, c Convention) (*Trivium, error) {
+	key, err := ParseKey(keyHex, c)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := ParseIV(ivHex, c)
+	if err != nil {
+		return nil, err
+	}
+	return NewTrivium(key, iv), nil
+}
+
+// parseHex parses 20 hex digits in convention c into the Raw byte order.
+func parseHex(s string, c Convention) ([KeyLength]byte, error) {
+	if len(s) != 2*KeyLength {
+		return [KeyLength]byte{}, errKeyHexLength
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return [KeyLength]byte{}, fmt.Errorf("trivium: invalid hex key or IV: %w", err)
+	}
+	return fromBytes(b, c)
+}
+
+// fromBytes converts 10 bytes in convention c into the Raw byte order.
+func fromBytes(b []byte, c Convention) ([KeyLength]byte, error) {
+	if len(b) != KeyLength {
+		return [KeyLength]byte{}, fmt.Errorf("trivium: key or IV must be %d bytes, got %d", KeyLength, len(b))
+	}
+	if c < Raw || c > ESTREAM {
+		return [KeyLength]byte{}, errors.New("trivium: unknown key convention " + c.String())
+	}
+	return reorder([KeyLength]byte(b), c), nil
+}
+
+// reorder converts between the Raw byte order and convention c, every conversion is its own inverse.
+func reorder(b [KeyLength]byte, c Convention) [KeyLength]byte {
+	var out [KeyLength]byte
+	switch c {
+	case Spec:
+		for i := range b {
+			out[i] = bits.Reverse8(b[i])
+		}
+	case ESTREAM:
+		for i := range b {
+			out[KeyLength-1-i] = bits.Reverse8(b[i])
+		}
+	case Raw:
+		out = b
+	default:
+		panic("trivium: unknown key convention " + c.String())
+	}
+	return out
+}
+
+// KeySizeError is returned for a key length in bits outside 0 to 80.
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "trivium: invalid key size " + strconv.Itoa(int(k)) + " bits, must be 0 to " + strconv.Itoa(KeyLength<<3)
+}
+
+// IVSizeError is returned for an IV length in bits outside 0 to 80.
+type IVSizeError int
+
+func (iv IVSizeError) Error() string {
+	return "trivium: invalid IV size " + strconv.Itoa(int(iv)) + " bits, must be 0 to " + strconv.Itoa(KeyLength<<3)
+}
+
+// NewTriviumBits returns a Trivium cipher for a key of keyBits bits and an IV of ivBits bits,
+// each from 0 to 80 bits.  The bits are in the Raw convention, bit i is bit i%8 of byte i/8,
+// and each slice must have exactly the bytes needed to hold its bits with any unused bits
+// of the last byte zero.  As in the specification the missing bits are padded with zeros,
+// so a shorter key or IV gives the same key stream as NewTrivium with the zero padded value.
+func NewTriviumBits(key []byte, keyBits int, iv []byte, ivBits int) (*Trivium, error) {
+	if keyBits < 0 || keyBits > KeyLength<<3 {
+		return nil, KeySizeError(keyBits)
+	}
+	if ivBits < 0 || ivBits > KeyLength<<3 {
+		return nil, IVSizeError(ivBits)
+	}
+	paddedKey, err := padBits(key, keyBits, "key")
+	if err != nil {
+		return nil, err
+	}
+	paddedIV, err := padBits(iv, ivBits, "IV")
+	if err != nil {
+		return nil, err
+	}
+	return NewTrivium(paddedKey, paddedIV), nil
+}
+
+// padBits zero pads the first n bits of b to the full 80 bits.
+func padBits(b []byte, n int, name string) ([KeyLength]byte, error) {
+	var padded [KeyLength]byte
+	if need := (n + 7) >> 3; len(b) != need {
+		return padded, fmt.Errorf("trivium: a %d-bit %s must be %d bytes, got %d", n, name, need, len(b))
+	}
+	copy(padded[:], b)
+	if n&7 != 0 && b[len(b)-1]>>(n&7) != 0 {
+		return padded, fmt.Errorf("trivium: a %d-bit %s has bits set after bit %d", n, name, n)
+	}
+	return padded, nil
+}
@@ -0,0 +1,228 @@
+package trivium
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/bmkessler/trivium/testvectors"
+)
+
+func ExampleNewTriviumESTREAM() {
+	// Set 6, vector# 3 of trivium-80.80.test-vectors
+	trivium, err := NewTriviumESTREAM("0F62B5085BAE0154A7FA", "288FF65DC42B92F960C7")
+	if err != nil {
+		panic(err)
+	}
+	stream := make([]byte, 16)
+	trivium.KeyStream(stream)
+	fmt.Printf("%X\n", stream)
+	// Output:
+	// A4386C6D7624983FEA8DBE7314E5FE1F
+}
+
+func TestKeyConventions(t *testing.T) {
+	// K1 is the first cell of the state in every convention
+	cases := []struct {
+		c   Convention
+		hex string
+	}{
+		{Raw, "01000000000000000000"},
+		{Spec, "80000000000000000000"},
+		{ESTREAM, "00000000000000000080"},
+	}
+	for _, c := range cases {
+		key, err := ParseKey(c.hex, c.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != (Key{0x01}) {
+			t.Errorf("%v: K1 parsed as %X", c.c, key)
+		}
+		if got := key.Format(c.c); got != c.hex {
+			t.Errorf("%v: K1 formatted as %s want %s", c.c, got, c.hex)
+		}
+		iv, err := ParseIV(c.hex, c.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if iv != (IV{0x01}) || iv.Format(c.c) != c.hex {
+			t.Errorf("%v: IV1 parsed as %X", c.c, iv)
+		}
+	}
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	key := Key{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	iv := IV{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	for _, c := range []Convention{Raw, Spec, ESTREAM} {
+		gotKey, err := KeyFromBytes(key.Bytes(c), c)
+		if err != nil || gotKey != key {
+			t.Errorf("%v: key bytes round trip got %X, %v", c, gotKey, err)
+		}
+		gotIV, err := IVFromBytes(iv.Bytes(c), c)
+		if err != nil || gotIV != iv {
+			t.Errorf("%v: IV bytes round trip got %X, %v", c, gotIV, err)
+		}
+		gotKey, err = ParseKey(key.Format(c), c)
+		if err != nil || gotKey != key {
+			t.Errorf("%v: key hex round trip got %X, %v", c, gotKey, err)
+		}
+	}
+	// Raw is the bytes of the key in order
+	if got := key.Format(Raw); got != "5FE52A8075DA10AD46F0" {
+		t.Errorf("Raw format %s", got)
+	}
+	if !bytes.Equal(key.Bytes(Raw), key[:]) {
+		t.Errorf("Raw bytes %X != %X", key.Bytes(Raw), key[:])
+	}
+}
+
+func TestNewTriviumConventions(t *testing.T) {
+	key := Key{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	iv := IV{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	want := NewTrivium(key, iv).NextUint64()
+	estream, err := NewTriviumESTREAM(key.Format(ESTREAM), iv.Format(ESTREAM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := NewTriviumSpec(key.Format(Spec), iv.Format(Spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estream.NextUint64() != want || spec.NextUint64() != want {
+		t.Errorf("NewTriviumESTREAM and NewTriviumSpec don't match NewTrivium")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	// Set 1, vector# 0 sets the key bit written 0x80 in the first byte, which is K73
+	got, err := Convert([]byte{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0}, ESTREAM, Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0x80}; !bytes.Equal(got, want) {
+		t.Errorf("Convert K73 from ESTREAM to Spec = %X, want %X", got, want)
+	}
+	if _, err := Convert(make([]byte, 10), Raw, Convention(42)); err == nil {
+		t.Errorf("expected an error for an unknown convention")
+	}
+	if _, err := Convert(make([]byte, 9), Raw, Spec); err == nil {
+		t.Errorf("expected an error for 9 bytes")
+	}
+
+	// every eSTREAM vector converted to the Spec convention gives the same key stream
+	file, err := os.Open(TestVectorFile8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	vectors, err := testvectors.Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors.Vectors {
+		key, err := Convert(v.Key, ESTREAM, Spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		iv, err := Convert(v.IV, ESTREAM, Spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trivium, err := NewTriviumSpec(hex.EncodeToString(key), hex.EncodeToString(iv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream := make([]byte, len(v.Chunks[0].Data))
+		trivium.KeyStream(stream)
+		if v.Chunks[0].Start != 0 || !bytes.Equal(stream, v.Chunks[0].Data) {
+			t.Errorf("%s: Spec key %X IV %X gives %X", v.Name(), key, iv, stream)
+		}
+	}
+}
+
+func TestKeyErrors(t *testing.T) {
+	for _, s := range []string{"", "0123", "0123456789012345678901", "0123456789ABCDEFGHIJ"} {
+		if _, err := ParseKey(s, Spec); err == nil {
+			t.Errorf("expected an error parsing key %q", s)
+		}
+		if _, err := NewTriviumESTREAM(s, "00000000000000000000"); err == nil {
+			t.Errorf("expected an error for NewTriviumESTREAM key %q", s)
+		}
+		if _, err := NewTriviumSpec("00000000000000000000", s); err == nil {
+			t.Errorf("expected an error for NewTriviumSpec IV %q", s)
+		}
+	}
+	if _, err := KeyFromBytes(make([]byte, 9), Raw); err == nil {
+		t.Errorf("expected an error for a 9 byte key")
+	}
+	if _, err := IVFromBytes(make([]byte, 10), Convention(42)); err == nil {
+		t.Errorf("expected an error for an unknown convention")
+	}
+	if Convention(42).String() != "Convention(42)" {
+		t.Errorf("unknown convention String() %s", Convention(42))
+	}
+}
+
+func TestNewTriviumBits(t *testing.T) {
+	key := Key{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	iv := IV{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	full, err := NewTriviumBits(key[:], 80, iv[:], 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.NextUint64() != NewTrivium(key, iv).NextUint64() {
+		t.Errorf("80-bit NewTriviumBits doesn't match NewTrivium")
+	}
+	// a 64-bit IV is the first 8 bytes padded with zeros
+	short, err := NewTriviumBits(key[:], 80, iv[:8], 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padded := iv
+	padded[8], padded[9] = 0, 0
+	if short.NextUint64() != NewTrivium(key, padded).NextUint64() {
+		t.Errorf("64-bit IV doesn't match the zero padded IV")
+	}
+	// bits that aren't a whole number of bytes
+	odd, err := NewTriviumBits([]byte{0x5F, 0x05}, 11, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if odd.NextUint64() != NewTrivium(Key{0x5F, 0x05}, IV{}).NextUint64() {
+		t.Errorf("11-bit key doesn't match the zero padded key")
+	}
+}
+
+func TestNewTriviumBitsErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		key     []byte
+		keyBits int
+		iv      []byte
+		ivBits  int
+		want    error
+	}{
+		{"key too long", make([]byte, 11), 81, nil, 0, KeySizeError(81)},
+		{"negative key", nil, -1, nil, 0, KeySizeError(-1)},
+		{"IV too long", nil, 0, make([]byte, 11), 88, IVSizeError(88)},
+		{"key bytes", make([]byte, 2), 8, nil, 0, nil},
+		{"IV bytes", nil, 0, make([]byte, 7), 64, nil},
+		{"key padding", []byte{0x10}, 4, nil, 0, nil},
+		{"IV padding", nil, 0, []byte{0xFF, 0x02}, 9, nil},
+	}
+	for _, c := range cases {
+		_, err := NewTriviumBits(c.key, c.keyBits, c.iv, c.ivBits)
+		if err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		} else if c.want != nil && err != c.want {
+			t.Errorf("%s: got %v want %v", c.name, err, c.want)
+		}
+	}
+	if got := KeySizeError(81).Error(); got != "trivium: invalid key size 81 bits, must be 0 to 80" {
+		t.Errorf("KeySizeError: %s", got)
+	}
+}
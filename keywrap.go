@@ -0,0 +1,57 @@
+package trivium
+
+import "errors"
+
+const (
+	// keyWrapVersion is bumped whenever the wrapped key format changes
+	keyWrapVersion = 1
+	// a wrapped key is the magic and the version, then the secret sealed with NewSIV with
+	// the magic and version as additional data
+	keyWrapMagic  = "trw"
+	keyWrapHeader = len(keyWrapMagic) + 1
+	// WrappedKeyLength bytes in a wrapped Trivium key
+	WrappedKeyLength = keyWrapHeader + KeyLength + SIVSize
+)
+
+var (
+	errInvalidWrappedKey  = errors.New("trivium: invalid wrapped key")
+	errUnknownWrapVersion = errors.New("trivium: unknown wrapped key version")
+)
+
+// WrapKey encrypts and authenticates secret, usually a Trivium key, under the key-encryption
+// key kek.  The wrapped key is "trw", a version byte, then secret sealed with NewSIV(kek)
+// with those four bytes as additional data, WrappedKeyLength bytes for a Trivium key.  As the
+// SIV mode needs no nonce, wrapping is deterministic: the same secret wrapped twice under the
+// same kek gives the same bytes, and nothing else is revealed.
+func WrapKey(kek [KeyLength]byte, secret []byte) []byte {
+	header := append([]byte(keyWrapMagic), keyWrapVersion)
+	return NewSIV(kek).Seal(header, nil, secret, header)
+}
+
+// UnwrapKey checks and decrypts a key wrapped by WrapKey under kek, returning an error if
+// wrapped was not produced with kek or has been modified.
+func UnwrapKey(kek [KeyLength]byte, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < keyWrapHeader || string(wrapped[:len(keyWrapMagic)]) != keyWrapMagic {
+		return nil, errInvalidWrappedKey
+	}
+	if wrapped[len(keyWrapMagic)] != keyWrapVersion {
+		return nil, errUnknownWrapVersion
+	}
+	secret, err := NewSIV(kek).Open(nil, nil, wrapped[keyWrapHeader:], wrapped[:keyWrapHeader])
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// UnwrapTriviumKey unwraps a Trivium key wrapped by WrapKey under kek, checking its length.
+func UnwrapTriviumKey(kek [KeyLength]byte, wrapped []byte) ([KeyLength]byte, error) {
+	if len(wrapped) != WrappedKeyLength {
+		return [KeyLength]byte{}, errInvalidWrappedKey
+	}
+	secret, err := UnwrapKey(kek, wrapped)
+	if err != nil {
+		return [KeyLength]byte{}, err
+	}
+	return [KeyLength]byte(secret), nil
+}
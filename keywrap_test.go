@@ -0,0 +1,79 @@
+package trivium
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyWrap(t *testing.T) {
+	var kek = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var key = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	wrapped := WrapKey(kek, key[:])
+	if len(wrapped) != WrappedKeyLength || string(wrapped[:4]) != "trw\x01" {
+		t.Fatalf("WrapKey = %X, want %d bytes starting trw and version 1", wrapped, WrappedKeyLength)
+	}
+	if bytes.Contains(wrapped, key[:]) {
+		t.Errorf("the wrapped key contains the key")
+	}
+	if !bytes.Equal(WrapKey(kek, key[:]), wrapped) {
+		t.Errorf("WrapKey is not deterministic")
+	}
+	// the body is the key sealed with the SIV mode, authenticating the header
+	if want := NewSIV(kek).Seal(nil, nil, key[:], wrapped[:4]); !bytes.Equal(wrapped[4:], want) {
+		t.Errorf("wrapped key body = %X, want %X", wrapped[4:], want)
+	}
+	got, err := UnwrapTriviumKey(kek, wrapped)
+	if err != nil || got != key {
+		t.Errorf("UnwrapTriviumKey = %X, %v, want %X", got, err, key)
+	}
+
+	// other secrets, including an empty one
+	for _, secret := range [][]byte{{}, []byte("a longer secret than a Trivium key")} {
+		got, err := UnwrapKey(kek, WrapKey(kek, secret))
+		if err != nil || !bytes.Equal(got, secret) {
+			t.Errorf("UnwrapKey of %q = %q, %v", secret, got, err)
+		}
+	}
+	if _, err := UnwrapTriviumKey(kek, WrapKey(kek, []byte("not a key"))); err == nil {
+		t.Errorf("UnwrapTriviumKey accepted a secret of the wrong length")
+	}
+}
+
+func TestKeyWrapTampering(t *testing.T) {
+	var kek = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var key = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	wrapped := WrapKey(kek, key[:])
+	for i := 0; i < len(wrapped)*8; i++ {
+		forged := append([]byte(nil), wrapped...)
+		forged[i/8] ^= 1 << (i % 8)
+		if _, err := UnwrapKey(kek, forged); err == nil {
+			t.Fatalf("wrapped key with bit %d flipped was accepted", i)
+		}
+	}
+	cases := map[string]error{
+		"":        errInvalidWrappedKey,
+		"trw":     errInvalidWrappedKey,
+		"xyz\x01": errInvalidWrappedKey,
+		"trw\x02": errUnknownWrapVersion,
+		"trw\x01": errOpen,
+	}
+	for wrapped, want := range cases {
+		if _, err := UnwrapKey(kek, []byte(wrapped)); err != want {
+			t.Errorf("UnwrapKey(%q) error %v, want %v", wrapped, err, want)
+		}
+	}
+	if _, err := UnwrapKey(kek, append(wrapped, 0)); err == nil {
+		t.Errorf("wrapped key with a byte appended was accepted")
+	}
+	if _, err := UnwrapKey(kek, wrapped[:len(wrapped)-1]); err == nil {
+		t.Errorf("truncated wrapped key was accepted")
+	}
+	// a wrapped key is not a valid wrapping under any other key-encryption key
+	for i := 0; i < KeyLength*8; i++ {
+		wrongKEK := kek
+		wrongKEK[i/8] ^= 1 << (i % 8)
+		if _, err := UnwrapTriviumKey(wrongKEK, wrapped); err == nil {
+			t.Fatalf("the key-encryption key with bit %d flipped was accepted", i)
+		}
+	}
+}
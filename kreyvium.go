@@ -0,0 +1,148 @@
+package trivium
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"math/bits"
+)
+
+// KreyviumKeyLength bytes in the Kreyvium key and IV, 16 bytes = 128 bits
+const KreyviumKeyLength = 16
+
+// Kreyvium is the Kreyvium cipher of Canteaut et al., a variant of Trivium with a 128-bit key
+// and IV.  The 288-bit state and its clocking are those of Trivium, except that each clock
+// also XORs a bit of the key into t3 and a bit of the IV into the feedback of t1.  The key and
+// IV bits are taken from two 128-bit registers that rotate by one bit every clock.
+//
+// The key stream is computed up to 64 bits at a time with the same SWAR code as Trivium.
+type Kreyvium struct {
+	state [5]uint64
+	key   [2]uint64 // the key bits used by the next 128 clocks, the next one in bit 0 of key[0]
+	iv    [2]uint64 // the IV bits used by the next 128 clocks, like key
+	count uint64    // number of key stream bits produced since initialization
+}
+
+// NewKreyvium returns a Kreyvium cipher initialized with key and initialization value (IV).
+// Both the key and IV are 128-bits (16 bytes), bit i of the key is bit i%8 of key[i/8] in the
+// same order as NewTrivium.  The initialization processes the cipher for 4*288 cycles.
+func NewKreyvium(key, iv [KreyviumKeyLength]byte) *Kreyvium {
+	var kreyvium Kreyvium
+	kreyvium.Reset(key, iv)
+	return &kreyvium
+}
+
+// NewKreyviumStream returns a cipher.Stream that encrypts or decrypts with the Kreyvium key
+// stream for the given key and initialization value (IV).
+func NewKreyviumStream(key, iv [KreyviumKeyLength]byte) cipher.Stream {
+	return NewKreyvium(key, iv)
+}
+
+// Reset re-initializes k in place with key and initialization value (IV), exactly as
+// NewKreyvium but without allocating.
+func (k *Kreyvium) Reset(key, iv [KreyviumKeyLength]byte) {
+	// reversing the bits of the key puts K1 in the MSB, the order of the state cells
+	k1 := bits.Reverse64(binary.LittleEndian.Uint64(key[:]))
+	k65 := bits.Reverse64(binary.LittleEndian.Uint64(key[8:]))
+	iv1 := bits.Reverse64(binary.LittleEndian.Uint64(iv[:]))
+	iv65 := bits.Reverse64(binary.LittleEndian.Uint64(iv[8:]))
+
+	// K1 to K93 fill cells 1 to 93, IV1 to IV128 cells 94 to 221 and cells 222 to 287 are set
+	k.state[0] = k1
+	k.state[1] = k65&^(^uint64(0)>>29) | iv1>>29
+	k.state[2] = iv1<<35 | iv65>>29
+	k.state[3] = iv65<<35 | ^uint64(0)>>29
+	k.state[4] = ^uint64(0) >> 33 << 33
+	// the registers are used from K128 down to K1, the reverse of the state order
+	k.key = [2]uint64{k65, k1}
+	k.iv = [2]uint64{iv65, iv1}
+
+	for i := 0; i < 4*288/wordSize; i++ {
+		k.NextUint64()
+	}
+	k.count = 0 // the warm-up is not part of the key stream
+}
+
+// NextBit gets the next bit from the Kreyvium stream.
+func (k *Kreyvium) NextBit() uint64 {
+	return k.NextBits(1)
+}
+
+// NextBits gets the next 1 to 64 bits from the Kreyvium stream.
+// The first bit produced is the least significant bit of the result.
+func (k *Kreyvium) NextBits(n uint) uint64 {
+	z := nextBits(&k.state, n, k.key[0], k.iv[0])
+	rotate128(&k.key, n)
+	rotate128(&k.iv, n)
+	k.count += uint64(n)
+	return z
+}
+
+// NextUint64 gets the next 64 bits from the Kreyvium stream, the first bit produced is the
+// least significant bit.
+func (k *Kreyvium) NextUint64() uint64 {
+	z := nextUint64(&k.state, k.key[0], k.iv[0])
+	k.key[0], k.key[1] = k.key[1], k.key[0]
+	k.iv[0], k.iv[1] = k.iv[1], k.iv[0]
+	k.count += wordSize
+	return z
+}
+
+// NextByte returns the next byte of key stream with the MSB as the last bit produced.
+func (k *Kreyvium) NextByte() byte {
+	return byte(k.NextBits(8))
+}
+
+// NextBytes returns the next 1 to 8 bytes of key stream with the MSB as the last bit produced.
+func (k *Kreyvium) NextBytes(n uint) []byte {
+	output := make([]byte, n)
+	word := k.NextBits(n << 3)
+	for i := uint(0); i < n; i++ {
+		output[i] = byte(word >> (i << 3))
+	}
+	return output
+}
+
+// KeyStream fills buf with the next len(buf) bytes of key stream, in the same order as
+// repeated calls to NextByte.
+func (k *Kreyvium) KeyStream(buf []byte) {
+	for ; len(buf) >= 8; buf = buf[8:] {
+		binary.LittleEndian.PutUint64(buf, k.NextUint64())
+	}
+	if len(buf) > 0 {
+		word := k.NextBits(uint(len(buf)) << 3)
+		for i := range buf {
+			buf[i] = byte(word >> (uint(i) << 3))
+		}
+	}
+}
+
+// XORKeyStream XORs each byte in src with a byte from the key stream and stores the result in dst.
+// dst and src must overlap entirely or not at all and len(dst) must be at least len(src).
+func (k *Kreyvium) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("trivium: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("trivium: invalid buffer overlap")
+	}
+	for ; len(src) >= 8; dst, src = dst[8:], src[8:] {
+		word := binary.LittleEndian.Uint64(src) ^ k.NextUint64()
+		binary.LittleEndian.PutUint64(dst, word)
+	}
+	if len(src) > 0 {
+		word := k.NextBits(uint(len(src)) << 3)
+		for i := range src {
+			dst[i] = src[i] ^ byte(word>>(uint(i)<<3))
+		}
+	}
+}
+
+// Count returns the number of key stream bits produced since k was initialized.
+func (k *Kreyvium) Count() uint64 {
+	return k.count
+}
+
+// rotate128 rotates the 128-bit register r, r[0] low, right by 1 to 64 bits.
+func rotate128(r *[2]uint64, n uint) {
+	r[0], r[1] = r[0]>>n|r[1]<<(wordSize-n), r[1]>>n|r[0]<<(wordSize-n)
+}
@@ -0,0 +1,170 @@
+package trivium
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+var (
+	testKreyviumKey = [KreyviumKeyLength]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0, 0x3C, 0x91, 0x0B, 0x7E, 0xC4, 0x28}
+	testKreyviumIV  = [KreyviumKeyLength]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14, 0x8A, 0x52, 0xD7, 0x39, 0x60, 0xAE}
+)
+
+// No published Kreyvium test vectors are checked in yet, the SWAR code is checked against
+// kreyviumReference, which computes n bits of key stream one clock at a time written directly
+// from the specification with one byte per cell and 1-based cell numbers.
+func kreyviumReference(key, iv [KreyviumKeyLength]byte, n int) []byte {
+	bit := func(b []byte, i int) byte { return b[(i-1)>>3] >> ((i - 1) & 7) & 1 }
+	var s [289]byte
+	var kstar, ivstar [128]byte
+	for i := 1; i <= 93; i++ {
+		s[i] = bit(key[:], i)
+	}
+	for i := 1; i <= 128; i++ {
+		s[93+i] = bit(iv[:], i)
+	}
+	for i := 222; i <= 287; i++ {
+		s[i] = 1
+	}
+	// (K*127, ..., K*0) = (K1, ..., K128)
+	for i := 0; i < 128; i++ {
+		kstar[i] = bit(key[:], 128-i)
+		ivstar[i] = bit(iv[:], 128-i)
+	}
+	z := make([]byte, 0, n)
+	for i := 1; i <= 4*288+n; i++ {
+		t1 := s[66] ^ s[93]
+		t2 := s[162] ^ s[177]
+		t3 := s[243] ^ s[288] ^ kstar[0]
+		if i > 4*288 {
+			z = append(z, t1^t2^t3)
+		}
+		t1 ^= s[91]&s[92] ^ s[171] ^ ivstar[0]
+		t2 ^= s[175]&s[176] ^ s[264]
+		t3 ^= s[286]&s[287] ^ s[69]
+		copy(s[2:94], s[1:93])
+		copy(s[95:178], s[94:177])
+		copy(s[179:289], s[178:288])
+		s[1], s[94], s[178] = t3, t1, t2
+		k0, iv0 := kstar[0], ivstar[0]
+		copy(kstar[:], kstar[1:])
+		copy(ivstar[:], ivstar[1:])
+		kstar[127], ivstar[127] = k0, iv0
+	}
+	return z
+}
+
+func TestKreyviumReference(t *testing.T) {
+	const bits = 1000
+	keys := [][2][KreyviumKeyLength]byte{
+		{},
+		{testKreyviumKey, testKreyviumIV},
+		{{0x80}, {}},
+		{{}, {15: 0x01}},
+	}
+	for _, keyIV := range keys {
+		want := kreyviumReference(keyIV[0], keyIV[1], bits)
+		k := NewKreyvium(keyIV[0], keyIV[1])
+		for i, w := range want {
+			if got := k.NextBit(); got != uint64(w) {
+				t.Fatalf("key %X IV %X: bit %d is %d, want %d", keyIV[0], keyIV[1], i, got, w)
+			}
+		}
+	}
+}
+
+func TestKreyviumBits(t *testing.T) {
+	var stream [512]byte
+	NewKreyvium(testKreyviumKey, testKreyviumIV).KeyStream(stream[:])
+	// mixing the sizes of NextBits and NextUint64 must give the same key stream
+	k := NewKreyvium(testKreyviumKey, testKreyviumIV)
+	var got []byte
+	var word, have uint64
+	for n := uint(1); len(got) < len(stream); n = (n+8)%64 + 1 {
+		var bits uint64
+		if n == 64 {
+			bits = k.NextUint64()
+		} else {
+			bits = k.NextBits(n)
+		}
+		for i := uint(0); i < n; i++ {
+			word |= (bits >> i & 1) << have
+			if have++; have == 8 {
+				got = append(got, byte(word))
+				word, have = 0, 0
+			}
+		}
+	}
+	if !bytes.Equal(got[:len(stream)], stream[:]) {
+		t.Errorf("NextBits key stream differs from KeyStream")
+	}
+	if k.Count() < uint64(len(stream))<<3 {
+		t.Errorf("Count() = %d, want at least %d", k.Count(), len(stream)<<3)
+	}
+}
+
+func TestKreyviumBytes(t *testing.T) {
+	var stream [64]byte
+	NewKreyvium(testKreyviumKey, testKreyviumIV).KeyStream(stream[:])
+	k := NewKreyvium(testKreyviumKey, testKreyviumIV)
+	for i := 0; i < 8; i++ {
+		if b := k.NextByte(); b != stream[i] {
+			t.Fatalf("NextByte %d = %02X, want %02X", i, b, stream[i])
+		}
+	}
+	for i := 8; i < len(stream); i += 7 {
+		n := min(7, len(stream)-i)
+		if b := k.NextBytes(uint(n)); !bytes.Equal(b, stream[i:i+n]) {
+			t.Fatalf("NextBytes at %d = %X, want %X", i, b, stream[i:i+n])
+		}
+	}
+}
+
+func TestKreyviumXORKeyStream(t *testing.T) {
+	var stream [333]byte
+	NewKreyvium(testKreyviumKey, testKreyviumIV).KeyStream(stream[:])
+	src := make([]byte, len(stream))
+	for i := range src {
+		src[i] = byte(i * 7)
+	}
+	var s cipher.Stream = NewKreyviumStream(testKreyviumKey, testKreyviumIV)
+	dst := make([]byte, len(src))
+	for i, n := 0, 1; i < len(src); i, n = i+n, n*2+1 {
+		n = min(n, len(src)-i)
+		s.XORKeyStream(dst[i:i+n], src[i:i+n])
+	}
+	for i := range dst {
+		if dst[i] != src[i]^stream[i] {
+			t.Fatalf("byte %d = %02X, want %02X", i, dst[i], src[i]^stream[i])
+		}
+	}
+	// decrypting in place restores the plaintext
+	NewKreyvium(testKreyviumKey, testKreyviumIV).XORKeyStream(dst, dst)
+	if !bytes.Equal(dst, src) {
+		t.Errorf("in place decryption failed")
+	}
+}
+
+func TestKreyviumReset(t *testing.T) {
+	k := NewKreyvium(testKreyviumKey, testKreyviumIV)
+	want := k.NextBytes(8)
+	k.Reset(testKreyviumKey, testKreyviumIV)
+	if got := k.NextBytes(8); !bytes.Equal(got, want) {
+		t.Errorf("after Reset got %X, want %X", got, want)
+	}
+	if k.Count() != 64 {
+		t.Errorf("Count() = %d, want 64", k.Count())
+	}
+}
+
+func BenchmarkKreyviumXORKeyStream(b *testing.B) {
+	var key, iv [KreyviumKeyLength]byte
+	k := NewKreyvium(key, iv)
+	buf := make([]byte, 1<<10)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k.XORKeyStream(buf, buf)
+	}
+}
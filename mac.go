@@ -0,0 +1,186 @@
+package trivium
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	// MACSize bytes in a tag of the MAC
+	MACSize = 16
+	// macKeySize bytes of key stream used as the one-time key of the MAC, r and s
+	macKeySize = 32
+	// macBlockSize bytes of message processed per multiplication
+	macBlockSize = 16
+
+	// r is clamped as in Poly1305, clearing the top four bits of every 32-bit word and the
+	// bottom two bits of the top three
+	rMask0 = 0x0FFFFFFC0FFFFFFF
+	rMask1 = 0x0FFFFFFC0FFFFFFC
+	// p = 2^130 - 5 as three words
+	p0 = 0xFFFFFFFFFFFFFFFB
+	p1 = 0xFFFFFFFFFFFFFFFF
+	p2 = 0x3
+)
+
+// MAC is the Poly1305 one-time authenticator, see RFC 8439, with its key taken from the start
+// of the Trivium key stream, a lightweight MAC that needs no other primitive.  The message is
+// evaluated as a polynomial in r modulo 2^130-5 and s is added to the result.
+//
+// The key is one-time: a (key, IV) pair must only ever authenticate a single message.  The MAC
+// key is the first 32 bytes of key stream, so a (key, IV) used for NewMAC must never also be
+// used with NewTrivium or NewStream, which would encrypt with those same bytes and reveal the
+// MAC key to anyone who knows the start of the plaintext.  To encrypt and authenticate with one
+// (key, IV) use NewMACWithTrivium, which continues the key stream after the MAC key.  MAC
+// implements hash.Hash, Reset starts the same message again with the same one-time key.
+type MAC struct {
+	r [2]uint64
+	s [2]uint64
+	h [3]uint64 // the accumulator, h[2] holds the bits above 128
+
+	buf    [macBlockSize]byte // a partial block waiting for more of the message
+	buffed int
+}
+
+// NewMAC returns a MAC keyed with the first 32 bytes of the key stream of Trivium with key and
+// IV, the first 16 bytes are r and the next 16 are s.  The (key, IV) must not be used for
+// anything else, see MAC.
+func NewMAC(key, iv [KeyLength]byte) *MAC {
+	m, _ := NewMACWithTrivium(key, iv)
+	return m
+}
+
+// NewMACWithTrivium returns the MAC of NewMAC together with the Trivium cipher it was keyed
+// from, already advanced past the 32 bytes of MAC key, so the rest of the key stream can
+// encrypt the message the MAC authenticates.
+func NewMACWithTrivium(key, iv [KeyLength]byte) (*MAC, *Trivium) {
+	t := NewTrivium(key, iv)
+	var macKey [macKeySize]byte
+	t.KeyStream(macKey[:])
+	return newPoly1305(macKey), t
+}
+
+// newPoly1305 returns a MAC with the 32-byte Poly1305 key r || s.
+func newPoly1305(key [macKeySize]byte) *MAC {
+	m := new(MAC)
+	m.r[0] = binary.LittleEndian.Uint64(key[0:8]) & rMask0
+	m.r[1] = binary.LittleEndian.Uint64(key[8:16]) & rMask1
+	m.s[0] = binary.LittleEndian.Uint64(key[16:24])
+	m.s[1] = binary.LittleEndian.Uint64(key[24:32])
+	return m
+}
+
+// Size returns the size of a tag, MACSize.
+func (m *MAC) Size() int {
+	return MACSize
+}
+
+// BlockSize returns the block size of the polynomial evaluation, 16 bytes.
+func (m *MAC) BlockSize() int {
+	return macBlockSize
+}
+
+// Reset starts a new message with the same one-time key.
+func (m *MAC) Reset() {
+	m.h = [3]uint64{}
+	m.buffed = 0
+}
+
+// Write adds p to the message, it never returns an error.
+func (m *MAC) Write(p []byte) (int, error) {
+	n := len(p)
+	if m.buffed > 0 {
+		k := copy(m.buf[m.buffed:], p)
+		m.buffed += k
+		p = p[k:]
+		if m.buffed < macBlockSize {
+			return n, nil
+		}
+		m.h = m.blocks(m.h, m.buf[:], true)
+		m.buffed = 0
+	}
+	whole := len(p) &^ (macBlockSize - 1)
+	m.h = m.blocks(m.h, p[:whole], true)
+	m.buffed = copy(m.buf[:], p[whole:])
+	return n, nil
+}
+
+// Sum appends the tag of the message so far to b, the MAC can continue to be written to.
+func (m *MAC) Sum(b []byte) []byte {
+	h := m.h
+	if m.buffed > 0 {
+		// a final partial block is padded with a single one bit and zeros instead of 2^128
+		var last [macBlockSize]byte
+		copy(last[:], m.buf[:m.buffed])
+		last[m.buffed] = 1
+		h = m.blocks(h, last[:], false)
+	}
+	// reduce h fully modulo p, subtracting p if h >= p in constant time
+	t0, borrow := bits.Sub64(h[0], p0, 0)
+	t1, borrow := bits.Sub64(h[1], p1, borrow)
+	_, borrow = bits.Sub64(h[2], p2, borrow)
+	keep := -borrow // all ones if h < p
+	h0 := h[0]&keep | t0&^keep
+	h1 := h[1]&keep | t1&^keep
+	// the tag is h + s modulo 2^128
+	h0, carry := bits.Add64(h0, m.s[0], 0)
+	h1, _ = bits.Add64(h1, m.s[1], carry)
+	var tag [MACSize]byte
+	binary.LittleEndian.PutUint64(tag[0:8], h0)
+	binary.LittleEndian.PutUint64(tag[8:16], h1)
+	return append(b, tag[:]...)
+}
+
+// Verify reports in constant time whether tag is the tag of the message written so far.
+func (m *MAC) Verify(tag []byte) bool {
+	var sum [MACSize]byte
+	return subtle.ConstantTimeCompare(m.Sum(sum[:0]), tag) == 1
+}
+
+// blocks adds each 16-byte block of msg to h and multiplies by r modulo 2^130-5, returning the
+// new h.  The 2^128 bit is added to every block when full is set.  h is kept only partially
+// reduced, below 2^131.
+func (m *MAC) blocks(h [3]uint64, msg []byte, full bool) [3]uint64 {
+	var high uint64
+	if full {
+		high = 1
+	}
+	r0, r1 := m.r[0], m.r[1]
+	for ; len(msg) >= macBlockSize; msg = msg[macBlockSize:] {
+		var c uint64
+		h[0], c = bits.Add64(h[0], binary.LittleEndian.Uint64(msg[0:8]), 0)
+		h[1], c = bits.Add64(h[1], binary.LittleEndian.Uint64(msg[8:16]), c)
+		h[2] += c + high
+
+		// h * r, r has its top four bits clear so the partial products cannot overflow
+		h0r0hi, h0r0lo := bits.Mul64(h[0], r0)
+		h1r0hi, h1r0lo := bits.Mul64(h[1], r0)
+		h0r1hi, h0r1lo := bits.Mul64(h[0], r1)
+		h1r1hi, h1r1lo := bits.Mul64(h[1], r1)
+		h2r0 := h[2] * r0
+		h2r1 := h[2] * r1
+
+		m1lo, c := bits.Add64(h1r0lo, h0r1lo, 0)
+		m1hi, _ := bits.Add64(h1r0hi, h0r1hi, c)
+		m2lo, c := bits.Add64(h1r1lo, h2r0, 0)
+		m2hi, _ := bits.Add64(h1r1hi, 0, c)
+
+		t0 := h0r0lo
+		t1, c := bits.Add64(h0r0hi, m1lo, 0)
+		t2, c := bits.Add64(m1hi, m2lo, c)
+		t3 := m2hi + h2r1 + c
+
+		// 2^130 = 5 modulo p, so the bits from 2^130 up are added back times 4 and times 1
+		h[0], h[1], h[2] = t0, t1, t2&3
+		cc0, cc1 := t2&^3, t3
+		h[0], c = bits.Add64(h[0], cc0, 0)
+		h[1], c = bits.Add64(h[1], cc1, c)
+		h[2] += c
+		cc0, cc1 = cc0>>2|cc1<<62, cc1>>2
+		h[0], c = bits.Add64(h[0], cc0, 0)
+		h[1], c = bits.Add64(h[1], cc1, c)
+		h[2] += c
+	}
+	return h
+}
@@ -0,0 +1,197 @@
+package trivium
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+var _ hash.Hash = (*MAC)(nil)
+
+func TestPoly1305(t *testing.T) {
+	// RFC 8439 section 2.5.2 and the all zero key of appendix A.3
+	cases := []struct {
+		key, msg, tag string
+	}{
+		{
+			"85d6be7857556d337f4452fe42d506a80103808afb0db2fd4abff6af4149f51b",
+			hex.EncodeToString([]byte("Cryptographic Forum Research Group")),
+			"a8061dc1305136c6c22b8baf0c0127a9",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			hex.EncodeToString(make([]byte, 64)),
+			"00000000000000000000000000000000",
+		},
+	}
+	for _, c := range cases {
+		key, _ := hex.DecodeString(c.key)
+		msg, _ := hex.DecodeString(c.msg)
+		tag, _ := hex.DecodeString(c.tag)
+		m := newPoly1305([macKeySize]byte(key))
+		m.Write(msg)
+		if got := m.Sum(nil); !bytes.Equal(got, tag) {
+			t.Errorf("Poly1305 of %q = %x, want %x", msg, got, tag)
+		}
+	}
+}
+
+// referencePoly1305 evaluates Poly1305 directly with big integers.
+func referencePoly1305(key [macKeySize]byte, msg []byte) []byte {
+	le := func(b []byte) *big.Int {
+		r := make([]byte, len(b))
+		for i := range b {
+			r[len(b)-1-i] = b[i]
+		}
+		return new(big.Int).SetBytes(r)
+	}
+	clamped := key
+	for _, i := range []int{3, 7, 11, 15} {
+		clamped[i] &= 0x0F
+	}
+	for _, i := range []int{4, 8, 12} {
+		clamped[i] &= 0xFC
+	}
+	r, s := le(clamped[:16]), le(key[16:])
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 130), big.NewInt(5))
+	h := new(big.Int)
+	for len(msg) > 0 {
+		n := min(len(msg), macBlockSize)
+		block := append(append([]byte(nil), msg[:n]...), 1)
+		h.Add(h, le(block))
+		h.Mul(h, r)
+		h.Mod(h, p)
+		msg = msg[n:]
+	}
+	h.Add(h, s)
+	tag := make([]byte, MACSize)
+	for i, b := range h.Bytes() {
+		if j := len(h.Bytes()) - 1 - i; j < MACSize {
+			tag[j] = b
+		}
+	}
+	return tag
+}
+
+func TestPoly1305Reference(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		var key [macKeySize]byte
+		rng.Read(key[:])
+		if i%4 == 0 {
+			// large accumulators exercise the final reduction
+			for j := range key[:16] {
+				key[j] = 0xFF
+			}
+		}
+		msg := make([]byte, rng.Intn(200))
+		rng.Read(msg)
+		if i%3 == 0 {
+			for j := range msg {
+				msg[j] = 0xFF
+			}
+		}
+		m := newPoly1305(key)
+		m.Write(msg)
+		if got, want := m.Sum(nil), referencePoly1305(key, msg); !bytes.Equal(got, want) {
+			t.Fatalf("Poly1305 with key %x of %x = %x, want %x", key, msg, got, want)
+		}
+	}
+}
+
+func TestMAC(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	msg := make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	// the one-time key is the first 32 bytes of the key stream
+	var macKey [macKeySize]byte
+	NewTrivium(key, iv).KeyStream(macKey[:])
+	want := referencePoly1305(macKey, msg)
+
+	m := NewMAC(key, iv)
+	if m.Size() != MACSize || m.BlockSize() != 16 {
+		t.Errorf("Size() = %d and BlockSize() = %d, want %d and 16", m.Size(), m.BlockSize(), MACSize)
+	}
+	// any split of the message gives the same tag
+	for _, split := range []int{0, 1, 15, 16, 17, 50, 100} {
+		m.Reset()
+		m.Write(msg[:split])
+		m.Write(msg[split:])
+		if got := m.Sum([]byte("prefix")); !bytes.Equal(got[6:], want) || string(got[:6]) != "prefix" {
+			t.Errorf("tag split at %d = %x, want %x", split, got[6:], want)
+		}
+		if !m.Verify(want) {
+			t.Errorf("Verify of the tag split at %d failed", split)
+		}
+	}
+	// Sum does not change the state
+	m.Reset()
+	m.Write(msg[:33])
+	m.Sum(nil)
+	m.Write(msg[33:])
+	if got := m.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("tag after an intermediate Sum = %x, want %x", got, want)
+	}
+
+	for i := 0; i < MACSize*8; i++ {
+		forged := append([]byte(nil), want...)
+		forged[i/8] ^= 1 << (i % 8)
+		if m.Verify(forged) {
+			t.Fatalf("tag with bit %d flipped was verified", i)
+		}
+	}
+	if m.Verify(want[:MACSize-1]) {
+		t.Errorf("a truncated tag was verified")
+	}
+	iv[0] ^= 1
+	other := NewMAC(key, iv)
+	other.Write(msg)
+	if other.Verify(want) {
+		t.Errorf("the tag verified with a different IV")
+	}
+}
+
+func TestMACWithTrivium(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	stream := make([]byte, macKeySize+100)
+	NewTrivium(key, iv).KeyStream(stream)
+
+	m, trivium := NewMACWithTrivium(key, iv)
+	// the cipher continues at byte 32, after the MAC key
+	if trivium.Count() != macKeySize*8 {
+		t.Errorf("Count() = %d, want %d", trivium.Count(), macKeySize*8)
+	}
+	got := make([]byte, 100)
+	trivium.KeyStream(got)
+	if !bytes.Equal(got, stream[macKeySize:]) {
+		t.Errorf("key stream after the MAC key = %X, want %X", got, stream[macKeySize:])
+	}
+	// and the MAC is the one of NewMAC, keyed with bytes 0 to 31
+	msg := []byte("attack at dawn")
+	m.Write(msg)
+	want := NewMAC(key, iv)
+	want.Write(msg)
+	if !bytes.Equal(m.Sum(nil), want.Sum(nil)) || !bytes.Equal(m.Sum(nil), referencePoly1305([macKeySize]byte(stream), msg)) {
+		t.Errorf("NewMACWithTrivium tag differs from NewMAC")
+	}
+}
+
+func BenchmarkMAC(b *testing.B) {
+	var key, iv [KeyLength]byte
+	buf := make([]byte, 1<<10)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m := NewMAC(key, iv)
+		m.Write(buf)
+		m.Sum(nil)
+	}
+}
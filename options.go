@@ -0,0 +1,111 @@
+package trivium
+
+import "fmt"
+
+// BitOrder is the order in which key stream bits are packed into bytes.
+type BitOrder int
+
+const (
+	// LSBFirst packs the first key stream bit into the least significant bit of each byte,
+	// the order of NewTrivium and the eSTREAM test vectors.
+	LSBFirst BitOrder = iota
+	// MSBFirst packs the first key stream bit into the most significant bit of each byte.
+	MSBFirst
+)
+
+// maxInitClocks is the largest number of initialization clocks accepted by WithInitClocks.
+const maxInitClocks = 1<<31 - 1
+
+// Option configures a Trivium cipher created with NewTriviumWithOptions.
+type Option func(*options) error
+
+// options holds the configuration built up by the Options, the zero value is not the default,
+// see defaultOptions.
+type options struct {
+	initClocks int
+	convention Convention
+	order      BitOrder
+}
+
+// defaultOptions is the configuration of NewTrivium.
+var defaultOptions = options{
+	initClocks: defaultInitClocks,
+	convention: Raw,
+	order:      LSBFirst,
+}
+
+// WithInitClocks sets the number of initialization clocks processed before the key stream,
+// 4*288 by default.  Reduced values such as 576, 672 or 799 are for cryptanalysis only.
+// The number is kept by Reset and is part of the encoding of MarshalBinary and MarshalText.
+func WithInitClocks(n int) Option {
+	return func(o *options) error {
+		if n < 0 || n > maxInitClocks {
+			return fmt.Errorf("trivium: invalid number of initialization clocks %d", n)
+		}
+		o.initClocks = n
+		return nil
+	}
+}
+
+// WithConvention sets how the key and IV bytes are loaded into the state, Raw by default.
+// Raw and Spec keys and IVs may be shorter than 10 bytes and are padded with zeros,
+// ESTREAM keys and IVs must be exactly 10 bytes.
+func WithConvention(c Convention) Option {
+	return func(o *options) error {
+		if c < Raw || c > ESTREAM {
+			return fmt.Errorf("trivium: unknown key convention %v", c)
+		}
+		o.convention = c
+		return nil
+	}
+}
+
+// WithBitOrder sets how the key stream is packed into bytes by NextByte, NextBytes, KeyStream
+// and XORKeyStream, LSBFirst by default.  NextBits and NextUint64 always return the first bit
+// in the least significant bit.  The bit order is kept by Reset and is part of the encoding of
+// MarshalBinary and MarshalText.
+func WithBitOrder(order BitOrder) Option {
+	return func(o *options) error {
+		if order != LSBFirst && order != MSBFirst {
+			return fmt.Errorf("trivium: unknown bit order %d", int(order))
+		}
+		o.order = order
+		return nil
+	}
+}
+
+// NewTriviumWithOptions returns a Trivium cipher for the key and IV configured by opts.
+// With no options it is the same as NewTrivium.
+func NewTriviumWithOptions(key, iv []byte, opts ...Option) (*Trivium, error) {
+	o := defaultOptions
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	loadedKey, err := loadBytes(key, o.convention, "key")
+	if err != nil {
+		return nil, err
+	}
+	loadedIV, err := loadBytes(iv, o.convention, "IV")
+	if err != nil {
+		return nil, err
+	}
+	t := Trivium{msbFirst: o.order == MSBFirst, extraInit: o.initClocks - defaultInitClocks}
+	t.Reset(loadedKey, loadedIV)
+	return &t, nil
+}
+
+// loadBytes converts a key or IV of up to 10 bytes in convention c to the Raw byte order.
+func loadBytes(b []byte, c Convention, name string) ([KeyLength]byte, error) {
+	if c == ESTREAM || len(b) > KeyLength {
+		if len(b) != KeyLength {
+			return [KeyLength]byte{}, fmt.Errorf("trivium: %v %s must be %d bytes, got %d", c, name, KeyLength, len(b))
+		}
+		return reorder([KeyLength]byte(b), c), nil
+	}
+	var padded [KeyLength]byte
+	copy(padded[:], b)
+	// the Raw and Spec conventions keep bytes in place, so padding commutes with reordering
+	return reorder(padded, c), nil
+}
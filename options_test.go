@@ -0,0 +1,128 @@
+package trivium
+
+import (
+	"bytes"
+	"math/bits"
+	"testing"
+)
+
+func TestNewTriviumWithOptionsDefault(t *testing.T) {
+	key := Key{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	iv := IV{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	trivium, err := NewTriviumWithOptions(key[:], iv[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *trivium != *NewTrivium(key, iv) {
+		t.Errorf("NewTriviumWithOptions without options doesn't match NewTrivium")
+	}
+}
+
+func TestWithInitClocks(t *testing.T) {
+	key := Key{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	iv := IV{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	for _, clocks := range []int{0, 1, 576, 672, 799} {
+		trivium, err := NewTriviumWithOptions(key[:], iv[:], WithInitClocks(clocks))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reduced Trivium
+		reduced.load(key, iv)
+		for i := 0; i < clocks; i++ {
+			reduced.NextBit()
+		}
+		if trivium.Count() != 0 || trivium.NextUint64() != reduced.NextUint64() {
+			t.Errorf("%d initialization clocks doesn't match %d calls of NextBit", clocks, clocks)
+		}
+		// Reset keeps the number of clocks
+		trivium.Reset(key, iv)
+		reduced.load(key, iv)
+		reduced.Discard(uint64(clocks))
+		if trivium.Count() != 0 || trivium.NextUint64() != reduced.NextUint64() {
+			t.Errorf("Reset after %d initialization clocks changed the number of clocks", clocks)
+		}
+	}
+}
+
+func TestWithConvention(t *testing.T) {
+	key := Key{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	iv := IV{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	want := NewTrivium(key, iv).NextUint64()
+	for _, c := range []Convention{Raw, Spec, ESTREAM} {
+		trivium, err := NewTriviumWithOptions(key.Bytes(c), iv.Bytes(c), WithConvention(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trivium.NextUint64() != want {
+			t.Errorf("%v convention doesn't match NewTrivium", c)
+		}
+	}
+	// short Spec keys are padded with zeros
+	trivium, err := NewTriviumWithOptions([]byte{0x80}, nil, WithConvention(Spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trivium.NextUint64() != NewTrivium(Key{0x01}, IV{}).NextUint64() {
+		t.Errorf("short Spec key doesn't match the padded key")
+	}
+}
+
+func TestWithBitOrder(t *testing.T) {
+	key := Key{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	iv := IV{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	lsb := make([]byte, 1100)
+	NewTrivium(key, iv).KeyStream(lsb)
+	want := make([]byte, len(lsb))
+	for i := range lsb {
+		want[i] = bits.Reverse8(lsb[i])
+	}
+
+	msbFirst := func() *Trivium {
+		trivium, err := NewTriviumWithOptions(key[:], iv[:], WithBitOrder(MSBFirst))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trivium
+	}
+	got := make([]byte, len(want))
+	msbFirst().KeyStream(got[:13])
+	trivium := msbFirst()
+	trivium.KeyStream(got[:13])
+	got[13] = trivium.NextByte()
+	copy(got[14:], trivium.NextBytes(3))
+	trivium.XORKeyStream(got[17:], got[17:]) // got is zero after 17
+	if !bytes.Equal(got, want) {
+		t.Errorf("MSBFirst key stream isn't the bit reversed LSBFirst key stream")
+	}
+	// NextBits is not affected by the bit order
+	if msbFirst().NextUint64() != NewTrivium(key, iv).NextUint64() {
+		t.Errorf("MSBFirst changed NextUint64")
+	}
+	// Reset keeps the bit order
+	trivium.Reset(key, iv)
+	if trivium.NextByte() != want[0] {
+		t.Errorf("Reset changed the bit order")
+	}
+}
+
+func TestNewTriviumWithOptionsErrors(t *testing.T) {
+	key := make([]byte, KeyLength)
+	cases := []struct {
+		name    string
+		key, iv []byte
+		opts    []Option
+	}{
+		{"clocks", key, key, []Option{WithInitClocks(-1)}},
+		{"too many clocks", key, key, []Option{WithInitClocks(maxInitClocks + 1)}},
+		{"convention", key, key, []Option{WithConvention(Convention(9))}},
+		{"bit order", key, key, []Option{WithBitOrder(BitOrder(2))}},
+		{"long key", make([]byte, 11), key, nil},
+		{"long IV", key, make([]byte, 11), []Option{WithConvention(Spec)}},
+		{"short ESTREAM key", key[:8], key, []Option{WithConvention(ESTREAM)}},
+	}
+	for _, c := range cases {
+		if _, err := NewTriviumWithOptions(c.key, c.iv, c.opts...); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
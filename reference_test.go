@@ -0,0 +1,144 @@
+package trivium
+
+import (
+	"bytes"
+	"testing"
+)
+
+// referenceTrivium is Trivium written directly from the pseudo-code of the specification, one
+// byte per cell with s[1] to s[288] and no shared constants, to check the SWAR code against.
+type referenceTrivium struct {
+	s [289]byte
+}
+
+// newReference loads key and iv as NewTrivium does, K1 is bit 0 of key[0], and runs the warm-up.
+func newReference(key, iv [KeyLength]byte) *referenceTrivium {
+	r := new(referenceTrivium)
+	// (s1, ..., s93) = (K1, ..., K80, 0, ..., 0)
+	// (s94, ..., s177) = (IV1, ..., IV80, 0, ..., 0)
+	// (s178, ..., s288) = (0, ..., 0, 1, 1, 1)
+	for i := 0; i < 80; i++ {
+		r.s[1+i] = key[i/8] >> (i % 8) & 1
+		r.s[94+i] = iv[i/8] >> (i % 8) & 1
+	}
+	r.s[286], r.s[287], r.s[288] = 1, 1, 1
+	for i := 0; i < 4*288; i++ {
+		r.clock()
+	}
+	return r
+}
+
+// clock runs one iteration of the key stream generation and returns z.
+func (r *referenceTrivium) clock() byte {
+	s := &r.s
+	t1 := s[66] ^ s[93]
+	t2 := s[162] ^ s[177]
+	t3 := s[243] ^ s[288]
+	z := t1 ^ t2 ^ t3
+	t1 = t1 ^ s[91]&s[92] ^ s[171]
+	t2 = t2 ^ s[175]&s[176] ^ s[264]
+	t3 = t3 ^ s[286]&s[287] ^ s[69]
+	// (s1, ..., s93) = (t3, s1, ..., s92)
+	for i := 93; i > 1; i-- {
+		s[i] = s[i-1]
+	}
+	s[1] = t3
+	// (s94, ..., s177) = (t1, s94, ..., s176)
+	for i := 177; i > 94; i-- {
+		s[i] = s[i-1]
+	}
+	s[94] = t1
+	// (s178, ..., s288) = (t2, s178, ..., s287)
+	for i := 288; i > 178; i-- {
+		s[i] = s[i-1]
+	}
+	s[178] = t2
+	return z
+}
+
+// bytes returns the next n bytes of key stream with the first bit in the LSB.
+func (r *referenceTrivium) bytes(n int) []byte {
+	out := make([]byte, n)
+	for i := range out {
+		for j := 0; j < 8; j++ {
+			out[i] |= r.clock() << j
+		}
+	}
+	return out
+}
+
+func TestReferenceExample(t *testing.T) {
+	// the first bytes of ExampleNewTrivium, which match the eSTREAM vectors
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	want := []byte{0xA4, 0x38, 0x6C, 0x6D, 0x76, 0x24, 0x98, 0x3F, 0xEA, 0x8D, 0xBE, 0x73, 0x14, 0xE5, 0xFE, 0x1F}
+	if got := newReference(key, iv).bytes(len(want)); !bytes.Equal(got, want) {
+		t.Errorf("reference key stream %X, want %X", got, want)
+	}
+}
+
+func TestReference(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	want := newReference(key, iv).bytes(1024)
+	got := make([]byte, len(want))
+	NewTrivium(key, iv).KeyStream(got)
+	if !bytes.Equal(got, want) {
+		t.Errorf("KeyStream differs from the reference")
+	}
+}
+
+// fuzzKeyIV turns arbitrary fuzz input into a key and IV, zero padding short inputs.
+func fuzzKeyIV(k, v []byte) (key, iv [KeyLength]byte) {
+	copy(key[:], k)
+	copy(iv[:], v)
+	return key, iv
+}
+
+// FuzzNextBits compares NextBits with the reference for a sequence of call widths, each byte
+// of widths selects a width of 1 to 64 bits.
+func FuzzNextBits(f *testing.F) {
+	f.Add([]byte{}, []byte{}, []byte{0, 63, 7, 31})
+	f.Add([]byte{0x80}, []byte{9: 0x01}, []byte{1, 2, 3, 64, 65})
+	f.Fuzz(func(t *testing.T, k, v, widths []byte) {
+		key, iv := fuzzKeyIV(k, v)
+		trivium := NewTrivium(key, iv)
+		ref := newReference(key, iv)
+		for _, w := range widths {
+			n := uint(w%wordSize) + 1
+			got := trivium.NextBits(n)
+			for j := uint(0); j < n; j++ {
+				if want := uint64(ref.clock()); got>>j&1 != want {
+					t.Fatalf("NextBits(%d) bit %d is %d, want %d", n, j, got>>j&1, want)
+				}
+			}
+			if n < wordSize && got>>n != 0 {
+				t.Fatalf("NextBits(%d) = %X has bits set above bit %d", n, got, n)
+			}
+		}
+	})
+}
+
+// FuzzNextBytes compares NextBytes and KeyStream with the reference for a sequence of call
+// lengths, each byte of lengths selects 1 to 8 bytes from NextBytes or up to 127 from KeyStream.
+func FuzzNextBytes(f *testing.F) {
+	f.Add([]byte{}, []byte{}, []byte{0, 7, 3})
+	f.Add([]byte{0x80}, []byte{9: 0x01}, []byte{1, 200, 8, 17})
+	f.Fuzz(func(t *testing.T, k, v, lengths []byte) {
+		key, iv := fuzzKeyIV(k, v)
+		trivium := NewTrivium(key, iv)
+		ref := newReference(key, iv)
+		for _, l := range lengths {
+			var got []byte
+			if l&1 == 0 {
+				got = trivium.NextBytes(uint(l>>1%8) + 1)
+			} else {
+				got = make([]byte, l>>1)
+				trivium.KeyStream(got)
+			}
+			if want := ref.bytes(len(got)); !bytes.Equal(got, want) {
+				t.Fatalf("got %X, want %X", got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package trivium
+
+import "errors"
+
+var errNotInitialState = errors.New("trivium: state does not rewind to a loaded key and IV")
+
+// PrevBit runs the state back one step and returns the key stream bit produced by that step.
+func (t *Trivium) PrevBit() uint64 {
+	return t.PrevBits(1)
+}
+
+// PrevBits runs the state back 1 to 64 steps, undoing NextBits.  The bits produced by those
+// steps are returned in the same order as NextBits, so PrevBits(n) after NextBits(n) returns
+// the same value and restores the state.  It panics if the state would be run back before
+// the start of the key stream.
+//
+// Each step back depends on the cell recovered by the step before it, so unlike NextBits
+// the state is run back one bit at a time.
+func (t *Trivium) PrevBits(n uint) uint64 {
+	if uint64(n) > t.count {
+		panic("trivium: PrevBits before the start of the key stream")
+	}
+	var z uint64
+	for i := n; i > 0; i-- {
+		z |= t.prevStep() << (i - 1)
+	}
+	t.count -= uint64(n)
+	return z
+}
+
+// RecoverKeyIV runs a copy of the state back to the start of the key stream and then through
+// its initialization clocks to recover the key and IV that were loaded by NewTrivium.  Anyone
+// holding the state at any point of the key stream can do this, which is why the state must
+// never leak.  An error is returned if the rewound state is not a valid loaded state.
+func (t *Trivium) RecoverKeyIV() (key, iv [KeyLength]byte, err error) {
+	rewind := t.Clone()
+	for i := uint64(0); i < t.count+uint64(t.initClocks()); i++ {
+		rewind.prevStep()
+	}
+	// the key is followed by zeros, the IV by zeros and finally three ones
+	for i := uint(KeyLength<<3 + 1); i <= 93; i++ {
+		if rewind.cell(i) != 0 {
+			return key, iv, errNotInitialState
+		}
+	}
+	for i := uint(93 + KeyLength<<3 + 1); i <= 288; i++ {
+		var want uint64
+		if i >= 286 {
+			want = 1
+		}
+		if rewind.cell(i) != want {
+			return key, iv, errNotInitialState
+		}
+	}
+	for i := uint(0); i < KeyLength<<3; i++ {
+		key[i>>3] |= byte(rewind.cell(i+1) << (i & 7))
+		iv[i>>3] |= byte(rewind.cell(i+94) << (i & 7))
+	}
+	return key, iv, nil
+}
+
+// prevStep undoes a single step of the state update and returns the key stream bit it produced.
+// The step shifted every cell one place and inserted t3, t1 and t2 at cells 1, 94 and 178, so
+// shifting back recovers every cell except the last cell of each register, which is solved for
+// from the inserted value and the other cells that contributed to it.
+func (t *Trivium) prevStep() uint64 {
+	t1 := t.cell(94)
+	t2 := t.cell(178)
+	t3 := t.cell(1)
+	// shift every cell back one place
+	for i := 0; i < len(t.state)-1; i++ {
+		t.state[i] = (t.state[i] << 1) | (t.state[i+1] >> mask)
+	}
+	t.state[len(t.state)-1] <<= 1
+	// solve for the cells that were shifted out of each register
+	s93 := t1 ^ t.cell(66) ^ (t.cell(91) & t.cell(92)) ^ t.cell(171)
+	s177 := t2 ^ t.cell(162) ^ (t.cell(175) & t.cell(176)) ^ t.cell(264)
+	s288 := t3 ^ t.cell(243) ^ (t.cell(286) & t.cell(287)) ^ t.cell(69)
+	t.setCell(93, s93)
+	t.setCell(177, s177)
+	t.setCell(288, s288)
+
+	return t.cell(66) ^ s93 ^ t.cell(162) ^ s177 ^ t.cell(243) ^ s288
+}
+
+// cell returns the value of cell i, numbered from 1 to 288 as in the specification.
+func (t *Trivium) cell(i uint) uint64 {
+	i--
+	return (t.state[i>>lgWordSize] >> (mask - (i & mask))) & 1
+}
+
+// setCell sets cell i, numbered from 1 to 288 as in the specification, to the bit v.
+func (t *Trivium) setCell(i uint, v uint64) {
+	i--
+	sh := mask - (i & mask)
+	t.state[i>>lgWordSize] = (t.state[i>>lgWordSize] &^ (1 << sh)) | (v << sh)
+}
@@ -0,0 +1,91 @@
+package trivium
+
+import "testing"
+
+func TestTriviumPrevBits(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	var trivium = NewTrivium(key, iv)
+	var totalBits uint = 4 * 288
+	forward := make([]uint64, totalBits)
+	for i := range forward {
+		forward[i] = trivium.NextBit()
+	}
+	end := trivium.Clone()
+	for width := uint(1); width <= wordSize; width++ {
+		trivium = end.Clone()
+		for i := totalBits; i >= width; i -= width {
+			bits := trivium.PrevBits(width)
+			for j := uint(0); j < width; j++ {
+				if want := forward[i-width+j]; (bits>>j)&1 != want {
+					t.Fatalf("PrevBits(%d) bit %d doesn't match %d != %d", width, i-width+j, (bits>>j)&1, want)
+				}
+			}
+		}
+	}
+	// a full run back returns to the initial state
+	trivium = end.Clone()
+	for trivium.Count() > 0 {
+		trivium.PrevBit()
+	}
+	got, _ := trivium.MarshalBinary()
+	want, _ := NewTrivium(key, iv).MarshalBinary()
+	if string(got) != string(want) {
+		t.Errorf("running back the key stream doesn't return to the initial state")
+	}
+}
+
+func TestTriviumPrevBitsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected PrevBits before the start of the key stream to panic")
+		}
+	}()
+	var key, iv [KeyLength]byte
+	trivium := NewTrivium(key, iv)
+	trivium.NextBits(3)
+	trivium.PrevBits(4)
+}
+
+func TestTriviumRecoverKeyIV(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	var trivium = NewTrivium(key, iv)
+	trivium.KeyStream(make([]byte, 1000))
+	trivium.NextBits(3)
+	gotKey, gotIV, err := trivium.RecoverKeyIV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotKey != key || gotIV != iv {
+		t.Errorf("recovered key %X iv %X want key %X iv %X", gotKey, gotIV, key, iv)
+	}
+	// the state itself is not changed
+	if trivium.Count() != 8003 {
+		t.Errorf("RecoverKeyIV changed the count to %d", trivium.Count())
+	}
+	// a state that was not loaded by NewTrivium
+	trivium.state[2] ^= 1 << 40
+	if _, _, err := trivium.RecoverKeyIV(); err != errNotInitialState {
+		t.Errorf("expected %v for a corrupted state, got %v", errNotInitialState, err)
+	}
+}
+
+func TestTriviumRecoverKeyIVInitClocks(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	for _, clocks := range []int{0, 576, 799, 4*288 + 100} {
+		trivium, err := NewTriviumWithOptions(key[:], iv[:], WithInitClocks(clocks))
+		if err != nil {
+			t.Fatal(err)
+		}
+		trivium.KeyStream(make([]byte, 100))
+		gotKey, gotIV, err := trivium.RecoverKeyIV()
+		if err != nil {
+			t.Fatalf("%d init clocks: %v", clocks, err)
+		}
+		if gotKey != key || gotIV != iv {
+			t.Errorf("%d init clocks: recovered key %X iv %X want key %X iv %X", clocks, gotKey, gotIV, key, iv)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package trivium
+
+import (
+	"errors"
+	"io"
+	"unsafe"
+)
+
+const (
+	// DefaultCheckpointSpacing bytes of key stream between checkpoints of a KeyStreamSeeker
+	DefaultCheckpointSpacing = 1 << 20
+	// DefaultCheckpointBudget bytes of memory used for the checkpoints of a KeyStreamSeeker
+	DefaultCheckpointBudget = 64 << 10
+
+	checkpointSize = int(unsafe.Sizeof(Trivium{}))
+)
+
+var (
+	errSeekEnd        = errors.New("trivium: the key stream has no end to seek from")
+	errWhence         = errors.New("trivium: invalid whence")
+	errNegativeOffset = errors.New("trivium: negative offset")
+)
+
+// KeyStreamSeeker gives random access to the key stream of a Trivium cipher.  As the key
+// stream is produced, the state is saved every spacing bytes, so a Seek only regenerates the
+// key stream from the nearest checkpoint at or before the new offset.  When the checkpoints
+// fill the memory budget, every other checkpoint is dropped and the spacing is doubled, so a
+// seek never regenerates more than spacing bytes within the part of the key stream seen so far.
+type KeyStreamSeeker struct {
+	trivium        Trivium // positioned at offset
+	offset         int64   // current byte offset in the key stream
+	spacing        int64   // bytes of key stream between checkpoints
+	maxCheckpoints int
+	checkpoints    []Trivium // checkpoints[i] is the state at byte offset i*spacing
+}
+
+// NewKeyStreamSeeker returns a KeyStreamSeeker over the key stream for the given key and IV,
+// positioned at the start of the key stream.  spacing is the initial number of key stream
+// bytes between checkpoints and budget is the memory in bytes available for checkpoints,
+// values less than or equal to zero select DefaultCheckpointSpacing and DefaultCheckpointBudget.
+func NewKeyStreamSeeker(key, iv [KeyLength]byte, spacing int64, budget int) *KeyStreamSeeker {
+	if spacing <= 0 {
+		spacing = DefaultCheckpointSpacing
+	}
+	if budget <= 0 {
+		budget = DefaultCheckpointBudget
+	}
+	// at least two checkpoints, and an even number so the spacing doubles cleanly
+	maxCheckpoints := budget / checkpointSize &^ 1
+	if maxCheckpoints < 2 {
+		maxCheckpoints = 2
+	}
+	s := &KeyStreamSeeker{
+		trivium:        *NewTrivium(key, iv),
+		spacing:        spacing,
+		maxCheckpoints: maxCheckpoints,
+		checkpoints:    make([]Trivium, 0, maxCheckpoints),
+	}
+	s.checkpoint()
+	return s
+}
+
+// Read fills p with the key stream at the current offset and advances the offset.
+// It always returns len(p) and a nil error.
+func (s *KeyStreamSeeker) Read(p []byte) (int, error) {
+	buf := p
+	s.forward(int64(len(buf)), func(n int64) {
+		s.trivium.KeyStream(buf[:n])
+		buf = buf[n:]
+	})
+	return len(p), nil
+}
+
+// XORKeyStream XORs src with the key stream at the current offset, stores the result in dst
+// and advances the offset, so a region in the middle of a message can be decrypted with a
+// Seek to its offset followed by XORKeyStream.
+func (s *KeyStreamSeeker) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("trivium: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("trivium: invalid buffer overlap")
+	}
+	s.forward(int64(len(src)), func(n int64) {
+		s.trivium.XORKeyStream(dst[:n], src[:n])
+		dst, src = dst[n:], src[n:]
+	})
+}
+
+// Seek implements io.Seeker over the key stream.  The key stream has no end, so only
+// io.SeekStart and io.SeekCurrent are supported.
+func (s *KeyStreamSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		return s.offset, errSeekEnd
+	default:
+		return s.offset, errWhence
+	}
+	if offset < 0 {
+		return s.offset, errNegativeOffset
+	}
+	nearest := offset / s.spacing
+	if nearest >= int64(len(s.checkpoints)) {
+		nearest = int64(len(s.checkpoints)) - 1
+	}
+	// restart from the checkpoint unless the current position is already closer
+	if s.offset > offset || s.offset < nearest*s.spacing {
+		s.trivium = s.checkpoints[nearest]
+		s.offset = nearest * s.spacing
+	}
+	s.forward(offset-s.offset, func(n int64) {
+		s.trivium.Discard(uint64(n) << 3)
+	})
+	return offset, nil
+}
+
+// forward advances the key stream n bytes, passing it to process in pieces that end at
+// checkpoint boundaries so that the checkpoints can be recorded along the way.
+func (s *KeyStreamSeeker) forward(n int64, process func(n int64)) {
+	for n > 0 {
+		piece := n
+		if next := int64(len(s.checkpoints)) * s.spacing; next > s.offset && next-s.offset < piece {
+			piece = next - s.offset
+		}
+		process(piece)
+		s.offset += piece
+		n -= piece
+		s.checkpoint()
+	}
+}
+
+// checkpoint records the state if the offset is at the next checkpoint to be recorded,
+// making room by dropping every other checkpoint when the budget is full.
+func (s *KeyStreamSeeker) checkpoint() {
+	if s.offset != int64(len(s.checkpoints))*s.spacing {
+		return
+	}
+	if len(s.checkpoints) == s.maxCheckpoints {
+		for i := 0; i < len(s.checkpoints)/2; i++ {
+			s.checkpoints[i] = s.checkpoints[2*i]
+		}
+		s.checkpoints = s.checkpoints[:len(s.checkpoints)/2]
+		s.spacing *= 2
+	}
+	s.checkpoints = append(s.checkpoints, s.trivium)
+}
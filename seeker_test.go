@@ -0,0 +1,85 @@
+package trivium
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+var _ io.ReadSeeker = (*KeyStreamSeeker)(nil)
+
+func TestKeyStreamSeeker(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	var totalBytes = 10000
+	want := make([]byte, totalBytes)
+	NewTrivium(key, iv).KeyStream(want)
+
+	// a small budget forces the spacing to double several times
+	seeker := NewKeyStreamSeeker(key, iv, 100, 6*checkpointSize)
+	offsets := []int64{0, 17, 5000, 4999, 9000, 123, 8001, 8000, 3333, 0, 7777, 2}
+	for _, offset := range offsets {
+		got, err := seeker.Seek(offset, io.SeekStart)
+		if err != nil || got != offset {
+			t.Fatalf("Seek(%d) returned %d, %v", offset, got, err)
+		}
+		buf := make([]byte, 1000)
+		if offset+int64(len(buf)) > int64(totalBytes) {
+			buf = buf[:int64(totalBytes)-offset]
+		}
+		if _, err := seeker.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, want[offset:offset+int64(len(buf))]) {
+			t.Errorf("key stream at offset %d doesn't match", offset)
+		}
+		if len(seeker.checkpoints) > seeker.maxCheckpoints {
+			t.Errorf("%d checkpoints exceeds the budget of %d", len(seeker.checkpoints), seeker.maxCheckpoints)
+		}
+	}
+	if seeker.spacing == 100 {
+		t.Errorf("expected the checkpoint spacing to grow beyond 100")
+	}
+
+	// relative seeks and XORKeyStream
+	if _, err := seeker.Seek(4000, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := seeker.Seek(-1000, io.SeekCurrent); err != nil || got != 3000 {
+		t.Fatalf("relative Seek returned %d, %v", got, err)
+	}
+	buf := make([]byte, 500)
+	seeker.XORKeyStream(buf, buf)
+	if !bytes.Equal(buf, want[3000:3500]) {
+		t.Errorf("XORKeyStream at offset 3000 doesn't match")
+	}
+}
+
+func TestKeyStreamSeekerErrors(t *testing.T) {
+	var key, iv [KeyLength]byte
+	seeker := NewKeyStreamSeeker(key, iv, 0, 0)
+	if _, err := seeker.Seek(0, io.SeekEnd); err != errSeekEnd {
+		t.Errorf("SeekEnd: got %v want %v", err, errSeekEnd)
+	}
+	if _, err := seeker.Seek(0, 42); err != errWhence {
+		t.Errorf("bad whence: got %v want %v", err, errWhence)
+	}
+	if _, err := seeker.Seek(-1, io.SeekStart); err != errNegativeOffset {
+		t.Errorf("negative offset: got %v want %v", err, errNegativeOffset)
+	}
+}
+
+func TestTriviumDiscard(t *testing.T) {
+	var key, iv [KeyLength]byte
+	for _, n := range []uint64{0, 1, 63, 64, 65, 1000} {
+		trivium := NewTrivium(key, iv)
+		discard := NewTrivium(key, iv)
+		for i := uint64(0); i < n; i++ {
+			trivium.NextBit()
+		}
+		discard.Discard(n)
+		if trivium.NextUint64() != discard.NextUint64() || discard.Count() != n+wordSize {
+			t.Errorf("Discard(%d) doesn't match NextBit", n)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package trivium
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// SegmentSize bytes of plaintext in every segment but the last of a segmented stream
+	SegmentSize = 64 << 10
+	// SegmentNonceSize bytes of nonce for a segmented stream, the rest of each segment's IV is
+	// the segment counter and the final segment flag
+	SegmentNonceSize = 5
+
+	// maxSegments is the number of segments the 32-bit counter allows
+	maxSegments = 1 << 32
+	// finalSegment is the flag in the last byte of the IV of the last segment
+	finalSegment = 1
+)
+
+var (
+	errTooManySegments = errors.New("trivium: too many segments for the segment counter")
+	errTruncated       = errors.New("trivium: segmented stream is truncated")
+	errClosed          = errors.New("trivium: segment writer is closed")
+)
+
+// segmentIV returns the IV of segment n, the nonce followed by n as a big-endian uint32 and
+// the final flag.
+func segmentIV(nonce [SegmentNonceSize]byte, n uint64, final bool) []byte {
+	iv := make([]byte, NonceSize)
+	copy(iv, nonce[:])
+	binary.BigEndian.PutUint32(iv[SegmentNonceSize:], uint32(n))
+	if final {
+		iv[NonceSize-1] = finalSegment
+	}
+	return iv
+}
+
+// SegmentWriter encrypts a stream in segments, see NewSegmentWriter.
+type SegmentWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	nonce [SegmentNonceSize]byte
+	n     uint64 // the number of the segment being filled
+	buf   []byte // the plaintext of the segment being filled, with room for the tag
+	err   error  // the first error, returned by every later Write and Close
+}
+
+// NewSegmentWriter returns a writer that encrypts everything written to it to w as a
+// segmented stream, the STREAM construction of Hoang, Reyhanitabar, Rogaway and Vizár.  The
+// plaintext is split into segments of SegmentSize bytes, the last of which may be shorter or
+// empty, and each is sealed separately with NewAEAD using the IV
+//
+//	nonce (5 bytes) || segment number (4 bytes, big-endian) || final flag (1 byte)
+//
+// The flag is one for the last segment only, so a reader detects truncation at a segment
+// boundary as well as reordered, deleted or modified segments, while only ever holding one
+// segment.  Close must be called to write the last segment, it does not close w.  A stream is
+// limited to 2^32 segments.
+//
+// The nonce must never be repeated with the same key.  With only 5 bytes, random nonces are
+// expected to collide after about 2^20 streams, so nonces must be a counter or otherwise
+// unique, not random.  Once a write to w fails, every later Write and Close returns the error.
+func NewSegmentWriter(w io.Writer, key [KeyLength]byte, nonce [SegmentNonceSize]byte) *SegmentWriter {
+	return &SegmentWriter{
+		w:     w,
+		aead:  NewAEAD(key),
+		nonce: nonce,
+		buf:   make([]byte, 0, SegmentSize+TagSize),
+	}
+}
+
+// Write encrypts p, writing each segment to the underlying writer once it is full and more
+// plaintext follows it.
+func (s *SegmentWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	written := 0
+	for len(p) > 0 {
+		// a full segment is only sealed once it is known not to be the last
+		if len(s.buf) == SegmentSize {
+			if err := s.flush(false); err != nil {
+				return written, err
+			}
+		}
+		k := copy(s.buf[len(s.buf):SegmentSize], p)
+		s.buf = s.buf[:len(s.buf)+k]
+		p = p[k:]
+		written += k
+	}
+	return written, nil
+}
+
+// Close seals and writes the last segment.  It does not close the underlying writer.
+func (s *SegmentWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.flush(true); err != nil {
+		return err
+	}
+	s.err = errClosed
+	return nil
+}
+
+// flush seals the buffered segment and writes it, recording the first error.
+func (s *SegmentWriter) flush(final bool) error {
+	if s.n >= maxSegments {
+		s.err = errTooManySegments
+		return s.err
+	}
+	sealed := s.aead.Seal(s.buf[:0], segmentIV(s.nonce, s.n, final), s.buf, nil)
+	s.n++
+	s.buf = s.buf[:0]
+	if _, err := s.w.Write(sealed); err != nil {
+		s.err = err
+		return err
+	}
+	return nil
+}
+
+// SegmentReader decrypts and authenticates a segmented stream, see NewSegmentReader.
+type SegmentReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	nonce  [SegmentNonceSize]byte
+	n      uint64 // the number of the next segment
+	buf    []byte // a sealed segment and the byte after it
+	ahead  byte   // the byte read after the previous segment, the first of the next
+	peeked bool   // ahead holds a byte
+	plain  []byte // the decrypted plaintext not yet read, in buf
+	err    error  // the error returned once plain is empty
+}
+
+// NewSegmentReader returns a reader that decrypts a segmented stream written by a
+// SegmentWriter with the same key and nonce.  Each segment is authenticated before any of its
+// plaintext is returned.  Read returns io.EOF only after the last segment has been
+// authenticated, a stream that is truncated, reordered or otherwise modified returns an error.
+func NewSegmentReader(r io.Reader, key [KeyLength]byte, nonce [SegmentNonceSize]byte) *SegmentReader {
+	return &SegmentReader{
+		r:     r,
+		aead:  NewAEAD(key),
+		nonce: nonce,
+		buf:   make([]byte, 0, SegmentSize+TagSize+1),
+	}
+}
+
+// Read reads decrypted plaintext into p.
+func (s *SegmentReader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		s.plain, s.err = s.next()
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+// next reads, authenticates and decrypts the next segment.  The byte after a full segment is
+// read ahead to tell whether it is the last.
+func (s *SegmentReader) next() ([]byte, error) {
+	if s.n >= maxSegments {
+		return nil, errTooManySegments
+	}
+	// the plaintext of the previous segment has all been read, so buf can be reused
+	s.buf = s.buf[:cap(s.buf)]
+	k := 0
+	if s.peeked {
+		s.buf[0] = s.ahead
+		k = 1
+	}
+	m, err := io.ReadFull(s.r, s.buf[k:])
+	s.buf = s.buf[:k+m]
+	final := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		final = true
+	case err != nil:
+		return nil, err
+	}
+	sealed := s.buf[:min(len(s.buf), SegmentSize+TagSize)]
+	if len(sealed) < TagSize {
+		return nil, errTruncated
+	}
+	plain, err := s.aead.Open(sealed[:0], segmentIV(s.nonce, s.n, final), sealed, nil)
+	if err != nil {
+		if final {
+			// the last segment received does not claim to be the last
+			if _, err := s.aead.Open(nil, segmentIV(s.nonce, s.n, false), sealed, nil); err == nil {
+				return nil, errTruncated
+			}
+		}
+		return nil, err
+	}
+	s.n++
+	if final {
+		return plain, io.EOF
+	}
+	s.ahead, s.peeked = s.buf[len(sealed)], true
+	return plain, nil
+}
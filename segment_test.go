@@ -0,0 +1,196 @@
+package trivium
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// sealSegments returns plaintext encrypted as a segmented stream, written in pieces of size step.
+func sealSegments(t *testing.T, key [KeyLength]byte, nonce [SegmentNonceSize]byte, plaintext []byte, step int) []byte {
+	t.Helper()
+	var sealed bytes.Buffer
+	w := NewSegmentWriter(&sealed, key, nonce)
+	for p := plaintext; len(p) > 0; {
+		n, err := w.Write(p[:min(step, len(p))])
+		if err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return sealed.Bytes()
+}
+
+// segments splits a segmented stream into its sealed segments.
+func segments(sealed []byte) [][]byte {
+	var s [][]byte
+	for len(sealed) > SegmentSize+TagSize {
+		s = append(s, sealed[:SegmentSize+TagSize])
+		sealed = sealed[SegmentSize+TagSize:]
+	}
+	return append(s, sealed)
+}
+
+func TestSegments(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var nonce = [SegmentNonceSize]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4}
+	for _, n := range []int{0, 1, SegmentSize - 1, SegmentSize, SegmentSize + 1, 3*SegmentSize + 100} {
+		plaintext := make([]byte, n)
+		for i := range plaintext {
+			plaintext[i] = byte(i * 7)
+		}
+		sealed := sealSegments(t, key, nonce, plaintext, 1000)
+		segs := segments(sealed)
+		if want := max(1, (n+SegmentSize-1)/SegmentSize); len(segs) != want {
+			t.Fatalf("%d bytes sealed in %d segments, want %d", n, len(segs), want)
+		}
+		// each segment is NewAEAD with the segment IV
+		aead := NewAEAD(key)
+		for i, seg := range segs {
+			final := i == len(segs)-1
+			got, err := aead.Open(nil, segmentIV(nonce, uint64(i), final), seg, nil)
+			if err != nil || !bytes.Equal(got, plaintext[i*SegmentSize:min(n, (i+1)*SegmentSize)]) {
+				t.Fatalf("segment %d of %d bytes does not open with its IV: %v", i, n, err)
+			}
+		}
+		if !bytes.Equal(sealSegments(t, key, nonce, plaintext, SegmentSize+5), sealed) {
+			t.Errorf("sealing %d bytes depends on the sizes written", n)
+		}
+
+		for _, step := range []int{1 << 20, 1000, 1} {
+			if step == 1 && n > SegmentSize {
+				continue
+			}
+			var opened bytes.Buffer
+			r := NewSegmentReader(bytes.NewReader(sealed), key, nonce)
+			buf := make([]byte, step)
+			for {
+				k, err := r.Read(buf)
+				opened.Write(buf[:k])
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("reading %d bytes: %v", n, err)
+				}
+			}
+			if !bytes.Equal(opened.Bytes(), plaintext) {
+				t.Fatalf("reading %d bytes in steps of %d did not return the plaintext", n, step)
+			}
+		}
+	}
+}
+
+func TestSegmentsTampering(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var nonce = [SegmentNonceSize]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4}
+	plaintext := make([]byte, 3*SegmentSize+100)
+	sealed := sealSegments(t, key, nonce, plaintext, len(plaintext))
+	segs := segments(sealed)
+	join := func(s ...[]byte) []byte {
+		return bytes.Join(s, nil)
+	}
+	flipped := append([]byte(nil), sealed...)
+	flipped[SegmentSize+TagSize+10] ^= 1
+
+	otherKey, otherNonce := key, nonce
+	otherKey[0] ^= 1
+	otherNonce[0] ^= 1
+
+	cases := []struct {
+		name   string
+		sealed []byte
+		key    [KeyLength]byte
+		nonce  [SegmentNonceSize]byte
+		want   error // nil for any error
+	}{
+		{"empty", nil, key, nonce, errTruncated},
+		{"truncated at a segment", join(segs[:2]...), key, nonce, errTruncated},
+		{"truncated in a segment", sealed[:len(sealed)-1], key, nonce, nil},
+		{"last segment deleted", join(segs[:3]...), key, nonce, errTruncated},
+		{"segment deleted", join(segs[0], segs[2], segs[3]), key, nonce, nil},
+		{"segments swapped", join(segs[1], segs[0], segs[2], segs[3]), key, nonce, nil},
+		{"segment repeated", join(segs[0], segs[0], segs[1], segs[2], segs[3]), key, nonce, nil},
+		{"bit flipped", flipped, key, nonce, nil},
+		{"appended", append(append([]byte(nil), sealed...), 0), key, nonce, nil},
+		{"stream appended", join(sealed, sealed), key, nonce, nil},
+		{"wrong key", sealed, otherKey, nonce, nil},
+		{"wrong nonce", sealed, key, otherNonce, nil},
+	}
+	for _, c := range cases {
+		r := NewSegmentReader(bytes.NewReader(c.sealed), c.key, c.nonce)
+		_, err := io.Copy(io.Discard, r)
+		switch {
+		case err == nil:
+			t.Errorf("%s: the stream was accepted", c.name)
+		case c.want != nil && !errors.Is(err, c.want):
+			t.Errorf("%s: error %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestSegmentWriterClosed(t *testing.T) {
+	var key [KeyLength]byte
+	var nonce [SegmentNonceSize]byte
+	w := NewSegmentWriter(io.Discard, key, nonce)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte{1}); err == nil {
+		t.Errorf("Write after Close succeeded")
+	}
+	if err := w.Close(); err == nil {
+		t.Errorf("second Close succeeded")
+	}
+}
+
+// failingWriter fails every write after the first n.
+type failingWriter struct {
+	n int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errWriteFailed
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestSegmentWriterError(t *testing.T) {
+	var key [KeyLength]byte
+	var nonce [SegmentNonceSize]byte
+	w := NewSegmentWriter(&failingWriter{n: 1}, key, nonce)
+	// the second segment is written when the third starts and fails
+	if _, err := w.Write(make([]byte, 2*SegmentSize+1)); err != errWriteFailed {
+		t.Fatalf("Write error %v, want %v", err, errWriteFailed)
+	}
+	// the error is sticky, no later segment is written and Close fails
+	if n, err := w.Write([]byte{1}); n != 0 || err != errWriteFailed {
+		t.Errorf("Write after a failure = %d, %v, want 0, %v", n, err, errWriteFailed)
+	}
+	if err := w.Close(); err != errWriteFailed {
+		t.Errorf("Close after a failure = %v, want %v", err, errWriteFailed)
+	}
+	if err := w.Close(); err != errWriteFailed {
+		t.Errorf("second Close after a failure = %v, want %v", err, errWriteFailed)
+	}
+}
+
+func BenchmarkSegmentWriter(b *testing.B) {
+	var key [KeyLength]byte
+	var nonce [SegmentNonceSize]byte
+	buf := make([]byte, SegmentSize)
+	b.SetBytes(int64(len(buf)))
+	w := NewSegmentWriter(io.Discard, key, nonce)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Write(buf)
+	}
+}
@@ -0,0 +1,99 @@
+package trivium
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/bmkessler/trivium/testvectors"
+)
+
+// selfTestVectors is one vector of each set of trivium-80.80.test-vectors.
+//
+//go:embed selftest-80.80.test-vectors
+var selfTestVectors string
+
+// selfTestWidths are the NextBits widths exercised by SelfTest, including ones that straddle
+// byte and word boundaries.
+var selfTestWidths = []uint{1, 3, 8, 13, 31, 57, 64}
+
+// SelfTest runs the known-answer tests embedded in the package, a subset of the eSTREAM test
+// vectors, as a power-on self-test.  Every vector is checked with KeyStream over its whole
+// stream including the digest, and its first bytes with NextBits at several widths, NextByte,
+// NextBytes and XORKeyStream.  The error identifies the vector and the API that failed.
+func SelfTest() error {
+	file, err := testvectors.Parse(bytes.NewReader([]byte(selfTestVectors)))
+	if err != nil {
+		return fmt.Errorf("trivium: self-test vectors: %w", err)
+	}
+	if len(file.Vectors) == 0 {
+		return errors.New("trivium: no self-test vectors")
+	}
+	for _, v := range file.Vectors {
+		if err := selfTest(v); err != nil {
+			return fmt.Errorf("trivium: self-test %s failed: %w", v.Name(), err)
+		}
+	}
+	return nil
+}
+
+// selfTest checks a single vector with every API.
+func selfTest(v *testvectors.Vector) error {
+	key, err := KeyFromBytes(v.Key, ESTREAM)
+	if err != nil {
+		return err
+	}
+	iv, err := IVFromBytes(v.IV, ESTREAM)
+	if err != nil {
+		return err
+	}
+	err = v.Verify(func(_, _, stream []byte) error {
+		NewTrivium(key, iv).KeyStream(stream)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("KeyStream: %w", err)
+	}
+	first := v.Chunks[0]
+	if first.Start != 0 {
+		return errors.New("the first chunk does not start the key stream")
+	}
+	want := first.Data
+
+	for _, n := range selfTestWidths {
+		trivium := NewTrivium(key, iv)
+		got := make([]byte, len(want))
+		for i := uint(0); i < uint(len(want))<<3; i += n {
+			width := min(n, uint(len(want))<<3-i)
+			bits := trivium.NextBits(width)
+			for j := uint(0); j < width; j++ {
+				got[(i+j)>>3] |= byte(bits>>j&1) << ((i + j) & 7)
+			}
+		}
+		if !bytes.Equal(got, want) {
+			return fmt.Errorf("NextBits(%d) = %X, want %X", n, got, want)
+		}
+	}
+
+	trivium := NewTrivium(key, iv)
+	got := make([]byte, 0, len(want))
+	for len(got) < len(want) {
+		if len(got)%2 == 0 {
+			got = append(got, trivium.NextByte())
+		} else {
+			n := min(len(got)%8+1, len(want)-len(got))
+			got = append(got, trivium.NextBytes(uint(n))...)
+		}
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("NextByte and NextBytes = %X, want %X", got, want)
+	}
+
+	got = make([]byte, len(want))
+	NewTrivium(key, iv).XORKeyStream(got, got)
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("XORKeyStream = %X, want %X", got, want)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package trivium
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSelfTestFailure(t *testing.T) {
+	saved := selfTestVectors
+	defer func() { selfTestVectors = saved }()
+
+	// a wrong digest and a wrong first chunk are both reported with the vector
+	selfTestVectors = strings.Replace(saved, "7AE3A4B53355061766122E04391EA1E6", "7AE3A4B53355061766122E04391EA1E7", 1)
+	if err := SelfTest(); err == nil || !strings.Contains(err.Error(), "Set 1, vector#  0") || !strings.Contains(err.Error(), "xor-digest") {
+		t.Errorf("SelfTest() = %v, want a digest failure of Set 1, vector#  0", err)
+	}
+	selfTestVectors = strings.Replace(saved, "38EB86FF", "38EB86FE", 1)
+	if err := SelfTest(); err == nil || !strings.Contains(err.Error(), "Set 1, vector#  0") {
+		t.Errorf("SelfTest() = %v, want a failure of Set 1, vector#  0", err)
+	}
+	selfTestVectors = "End of test vectors"
+	if err := SelfTest(); err == nil {
+		t.Errorf("SelfTest() with no vectors succeeded")
+	}
+	selfTestVectors = "garbage"
+	if err := SelfTest(); err == nil {
+		t.Errorf("SelfTest() with unparsable vectors succeeded")
+	}
+}
+
+func BenchmarkSelfTest(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		SelfTest()
+	}
+}
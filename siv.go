@@ -0,0 +1,103 @@
+package trivium
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// SIVSize bytes of synthetic IV appended by the SIV mode, a whole Trivium IV
+const SIVSize = KeyLength
+
+// sivMACIV is the IV of the key stream the SIV MAC key is taken from.  The high bit of its last
+// byte is set and that bit of every synthetic IV is clear, so no message of the SIV mode is
+// encrypted with that key stream.  Other modes can reach it, see NewSIV.
+var sivMACIV = [KeyLength]byte{KeyLength - 1: 0x80}
+
+// siv is Trivium in a synthetic IV mode, see NewSIV.
+type siv struct {
+	key    [KeyLength]byte
+	macKey [sha256.Size]byte
+}
+
+// NewSIV returns a deterministic, nonce-misuse-resistant cipher.AEAD using Trivium.  The IV
+// for each message is synthetic, the first 10 bytes of the HMAC-SHA256 of the additional data
+// and the plaintext with the high bit of the last byte cleared, and is appended to the
+// ciphertext as its tag.  The HMAC key is the first 32 bytes of the key stream of key with an
+// IV no message uses.
+//
+// There is no nonce, NonceSize is zero: sealing the same plaintext and additional data twice
+// gives the same ciphertext, revealing only that they were equal.  Different messages get
+// different IVs unless their truncated HMACs collide, as the IV has 79 bits two messages are
+// expected to share a key stream after about 2^39.5 messages under one key, so far fewer than
+// that should be sealed.  A random or counter value can be included in the additional data to
+// hide repeated messages.  Open decrypts before it can check the tag, the plaintext is cleared
+// again if the check fails.
+//
+// A SIV key must not be used with any other mode in this package.  The HMAC key is the start
+// of the key stream for the IV 00000000000000000080, the same bytes that NewStream encrypts
+// with and that NewAEAD and NewMAC take as their MAC key for that IV.
+func NewSIV(key [KeyLength]byte) cipher.AEAD {
+	s := &siv{key: key}
+	NewTrivium(key, sivMACIV).KeyStream(s.macKey[:])
+	return s
+}
+
+// NonceSize returns zero, the IV is derived from the message.
+func (s *siv) NonceSize() int {
+	return 0
+}
+
+// Overhead returns the size of the synthetic IV.
+func (s *siv) Overhead() int {
+	return SIVSize
+}
+
+// Seal encrypts and authenticates plaintext, authenticates additionalData and appends the
+// result to dst.
+func (s *siv) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 0 {
+		panic("trivium: incorrect nonce length given to SIV")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+SIVSize)
+	ciphertext, tag := out[:len(plaintext)], out[len(plaintext):]
+	if inexactOverlap(out, plaintext) {
+		panic("trivium: invalid buffer overlap")
+	}
+	iv := s.syntheticIV(additionalData, plaintext)
+	NewTrivium(s.key, iv).XORKeyStream(ciphertext, plaintext)
+	copy(tag, iv[:])
+	return ret
+}
+
+// Open decrypts ciphertext with the IV at its end and checks that the IV is the one derived
+// from the plaintext and additionalData, appending the plaintext to dst.
+func (s *siv) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != 0 {
+		panic("trivium: incorrect nonce length given to SIV")
+	}
+	if len(ciphertext) < SIVSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-SIVSize:]
+	ciphertext = ciphertext[:len(ciphertext)-SIVSize]
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	if inexactOverlap(out, ciphertext) {
+		panic("trivium: invalid buffer overlap")
+	}
+	NewTrivium(s.key, [KeyLength]byte(tag)).XORKeyStream(out, ciphertext)
+	if iv := s.syntheticIV(additionalData, out); !hmac.Equal(iv[:], tag) {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// syntheticIV returns the IV for a message, its HMAC truncated to an IV with the high bit of
+// the last byte clear.
+func (s *siv) syntheticIV(additionalData, plaintext []byte) [KeyLength]byte {
+	var sum [sha256.Size]byte
+	iv := [KeyLength]byte(authenticate(sum[:0], s.macKey[:], additionalData, plaintext))
+	iv[KeyLength-1] &^= 0x80
+	return iv
+}
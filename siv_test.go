@@ -0,0 +1,167 @@
+package trivium
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestSIV(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	aead := NewSIV(key)
+	if aead.NonceSize() != 0 || aead.Overhead() != 10 {
+		t.Errorf("NonceSize() = %d and Overhead() = %d, want 0 and 10", aead.NonceSize(), aead.Overhead())
+	}
+	ad := []byte("header")
+	for _, n := range []int{0, 1, 31, 64, 1000} {
+		plaintext := make([]byte, n)
+		for i := range plaintext {
+			plaintext[i] = byte(i)
+		}
+		sealed := aead.Seal([]byte("prefix"), nil, plaintext, ad)
+		ciphertext := sealed[6:]
+		if string(sealed[:6]) != "prefix" || len(ciphertext) != n+SIVSize {
+			t.Fatalf("Seal of %d bytes gave %d bytes", n, len(sealed))
+		}
+
+		// the same inputs give the same ciphertext, encrypted with the IV at the end
+		if again := aead.Seal(nil, nil, plaintext, ad); !bytes.Equal(again, ciphertext) {
+			t.Errorf("Seal of %d bytes is not deterministic", n)
+		}
+		iv := [KeyLength]byte(ciphertext[n:])
+		if iv[KeyLength-1]&0x80 != 0 {
+			t.Errorf("synthetic IV %X has the bit reserved for the MAC key set", iv)
+		}
+		stream := make([]byte, n)
+		NewTrivium(key, iv).KeyStream(stream)
+		for i := range plaintext {
+			if ciphertext[i] != plaintext[i]^stream[i] {
+				t.Fatalf("ciphertext byte %d is not encrypted with the synthetic IV", i)
+			}
+		}
+
+		opened, err := aead.Open(nil, nil, ciphertext, ad)
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Fatalf("Open of %d bytes = %X, %v", n, opened, err)
+		}
+		// in place
+		buf := append([]byte(nil), plaintext...)
+		buf = aead.Seal(buf[:0], nil, buf, ad)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Errorf("in place Seal differs")
+		}
+		if buf, err = aead.Open(buf[:0], nil, buf, ad); err != nil || !bytes.Equal(buf, plaintext) {
+			t.Errorf("in place Open = %X, %v", buf, err)
+		}
+	}
+}
+
+func TestSIVDistinct(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	aead := NewSIV(key)
+	// messages differing in a single bit of the plaintext or additional data, or only in where
+	// the additional data ends, all get different IVs
+	seen := make(map[string]string)
+	check := func(name string, plaintext, ad []byte) {
+		sealed := aead.Seal(nil, nil, plaintext, ad)
+		iv := string(sealed[len(plaintext):])
+		if other, ok := seen[iv]; ok {
+			t.Errorf("%s and %s have the same synthetic IV", name, other)
+		}
+		seen[iv] = name
+	}
+	plaintext := []byte("attack at dawn")
+	ad := []byte("additional data")
+	check("original", plaintext, ad)
+	for i := 0; i < len(plaintext)*8; i++ {
+		p := append([]byte(nil), plaintext...)
+		p[i/8] ^= 1 << (i % 8)
+		check(fmt.Sprintf("plaintext bit %d", i), p, ad)
+	}
+	for i := 0; i < len(ad)*8; i++ {
+		a := append([]byte(nil), ad...)
+		a[i/8] ^= 1 << (i % 8)
+		check(fmt.Sprintf("additional data bit %d", i), plaintext, a)
+	}
+	check("moved byte", plaintext[1:], append(ad, plaintext[0]))
+	var otherKey = key
+	otherKey[0] ^= 1
+	if bytes.Equal(NewSIV(otherKey).Seal(nil, nil, plaintext, ad), aead.Seal(nil, nil, plaintext, ad)) {
+		t.Errorf("a different key gave the same ciphertext")
+	}
+}
+
+func TestSIVForgery(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	aead := NewSIV(key)
+	ad := []byte("additional data")
+	ciphertext := aead.Seal(nil, nil, []byte("attack at dawn"), ad)
+	// a fixed answer so that the derivation of the IV cannot change unnoticed
+	if want, _ := hex.DecodeString("3DF84A3B82638220F0DA3F0AC7E44302AE356A896CDEC428"); !bytes.Equal(ciphertext, want) {
+		t.Errorf("Seal = %X, want %X", ciphertext, want)
+	}
+	for i := 0; i < len(ciphertext)*8; i++ {
+		forged := append([]byte(nil), ciphertext...)
+		forged[i/8] ^= 1 << (i % 8)
+		dst := make([]byte, 0, len(ciphertext))
+		if _, err := aead.Open(dst, nil, forged, ad); err == nil {
+			t.Fatalf("ciphertext with bit %d flipped was accepted", i)
+		}
+		// the decrypted plaintext is not left behind in dst
+		if !bytes.Equal(dst[:len(ciphertext)-SIVSize], make([]byte, len(ciphertext)-SIVSize)) {
+			t.Fatalf("plaintext was left in dst after bit %d flipped", i)
+		}
+	}
+	for i := 0; i < len(ad)*8; i++ {
+		forged := append([]byte(nil), ad...)
+		forged[i/8] ^= 1 << (i % 8)
+		if _, err := aead.Open(nil, nil, ciphertext, forged); err == nil {
+			t.Fatalf("additional data with bit %d flipped was accepted", i)
+		}
+	}
+	if _, err := aead.Open(nil, nil, ciphertext[:SIVSize-1], ad); err == nil {
+		t.Errorf("a ciphertext shorter than the IV was accepted")
+	}
+	var wrongKey = key
+	wrongKey[0] ^= 1
+	if _, err := NewSIV(wrongKey).Open(nil, nil, ciphertext, ad); err == nil {
+		t.Errorf("the wrong key was accepted")
+	}
+}
+
+func TestSIVPanics(t *testing.T) {
+	var key [KeyLength]byte
+	aead := NewSIV(key)
+	buf := make([]byte, 100)
+	cases := map[string]func(){
+		"nonce Seal":       func() { aead.Seal(nil, make([]byte, 1), nil, nil) },
+		"nonce Open":       func() { aead.Open(nil, make([]byte, 10), make([]byte, 40), nil) },
+		"overlapping Seal": func() { aead.Seal(buf[:1], nil, buf[:20], nil) },
+		"overlapping Open": func() {
+			aead.Open(buf[40:41], nil, aead.Seal(buf[40:40], nil, buf[:10], nil), nil)
+		},
+	}
+	for name, f := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func BenchmarkSIVSeal(b *testing.B) {
+	var key [KeyLength]byte
+	var aead cipher.AEAD = NewSIV(key)
+	buf := make([]byte, 1<<10, 1<<10+SIVSize)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		aead.Seal(buf[:0], nil, buf, nil)
+	}
+}
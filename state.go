@@ -0,0 +1,187 @@
+package trivium
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// stateLength bytes in the encoded 288-bit state
+	stateLength = 288 >> 3
+	// encodingVersion is bumped whenever the binary or text encoding changes, version 1 had
+	// no bit order or initialization clocks and is still accepted
+	encodingVersion = 2
+	// the binary encoding is the magic, the version, the state, the key stream bit count, the
+	// bit order then the initialization clocks
+	magic           = "trv"
+	marshaledSizeV1 = len(magic) + 1 + stateLength + 8
+	marshaledSize   = marshaledSizeV1 + 1 + 8
+	// the text encoding is the text magic, the version, then ":state:count:order:clocks"
+	textMagic = "trivium/"
+)
+
+var (
+	errInvalidEncoding = errors.New("trivium: invalid state encoding")
+	errUnknownVersion  = errors.New("trivium: unknown state encoding version")
+)
+
+// Clone returns an independent copy of t that continues the key stream from the same position.
+func (t *Trivium) Clone() *Trivium {
+	clone := *t
+	return &clone
+}
+
+// Count returns the number of key stream bits produced since initialization.
+func (t *Trivium) Count() uint64 {
+	return t.count
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.  The encoding holds the full 288-bit
+// state and the key stream bit count, so anyone holding it can continue (or recover) the
+// key stream, it must be protected like the key itself.  The bit order and the number of
+// initialization clocks set by NewTriviumWithOptions are included.
+func (t *Trivium) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, marshaledSize)
+	b = append(b, magic...)
+	b = append(b, encodingVersion)
+	b = t.appendState(b)
+	b = binary.BigEndian.AppendUint64(b, t.count)
+	b = append(b, byte(t.bitOrder()))
+	b = binary.BigEndian.AppendUint64(b, uint64(t.initClocks()))
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler restoring a state produced by
+// MarshalBinary.  A version 1 encoding has no bit order or initialization clocks, those of t
+// are kept.
+func (t *Trivium) UnmarshalBinary(b []byte) error {
+	if len(b) <= len(magic) || string(b[:len(magic)]) != magic {
+		return errInvalidEncoding
+	}
+	version := b[len(magic)]
+	switch {
+	case version != 1 && version != encodingVersion:
+		return errUnknownVersion
+	case version == 1 && len(b) != marshaledSizeV1, version == encodingVersion && len(b) != marshaledSize:
+		return errInvalidEncoding
+	}
+	b = b[len(magic)+1:]
+	if version == encodingVersion {
+		order, clocks := BitOrder(b[stateLength+8]), binary.BigEndian.Uint64(b[stateLength+9:])
+		if err := t.setOptions(order, clocks); err != nil {
+			return err
+		}
+	}
+	t.loadState(b[:stateLength])
+	t.count = binary.BigEndian.Uint64(b[stateLength:])
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.  The text form is the version prefix
+// "trivium/2:", the 288-bit state as hex with cell 1 as the most significant bit, a colon,
+// the decimal key stream bit count, then ":lsb" or ":msb" for the bit order and a colon and
+// the decimal number of initialization clocks.
+func (t *Trivium) MarshalText() ([]byte, error) {
+	b := make([]byte, 0, len(textMagic)+2*stateLength+48)
+	b = append(b, textMagic...)
+	b = strconv.AppendUint(b, encodingVersion, 10)
+	b = append(b, ':')
+	b = hex.AppendEncode(b, t.appendState(make([]byte, 0, stateLength)))
+	b = append(b, ':')
+	b = strconv.AppendUint(b, t.count, 10)
+	b = append(b, ':')
+	b = append(b, orderNames[t.bitOrder()]...)
+	b = append(b, ':')
+	b = strconv.AppendUint(b, uint64(t.initClocks()), 10)
+	return b, nil
+}
+
+// orderNames are the bit orders in the text encoding.
+var orderNames = map[BitOrder]string{LSBFirst: "lsb", MSBFirst: "msb"}
+
+// bitOrder returns the order t packs key stream bits into bytes.
+func (t *Trivium) bitOrder() BitOrder {
+	if t.msbFirst {
+		return MSBFirst
+	}
+	return LSBFirst
+}
+
+// setOptions sets the bit order and initialization clocks of a decoded state.
+func (t *Trivium) setOptions(order BitOrder, clocks uint64) error {
+	if (order != LSBFirst && order != MSBFirst) || clocks > maxInitClocks {
+		return errInvalidEncoding
+	}
+	t.msbFirst = order == MSBFirst
+	t.extraInit = int(clocks) - defaultInitClocks
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler restoring a state produced by MarshalText.
+// A version 1 encoding has no bit order or initialization clocks, those of t are kept.
+func (t *Trivium) UnmarshalText(text []byte) error {
+	s, ok := strings.CutPrefix(string(text), textMagic)
+	if !ok {
+		return errInvalidEncoding
+	}
+	fields := strings.Split(s, ":")
+	version, err := strconv.ParseUint(fields[0], 10, 8)
+	switch {
+	case err != nil:
+		return errInvalidEncoding
+	case version != 1 && version != encodingVersion:
+		return errUnknownVersion
+	case version == 1 && len(fields) != 3, version == encodingVersion && len(fields) != 5:
+		return errInvalidEncoding
+	}
+	stateHex, countText := fields[1], fields[2]
+	if len(stateHex) != 2*stateLength {
+		return errInvalidEncoding
+	}
+	state, err := hex.DecodeString(stateHex)
+	if err != nil {
+		return errInvalidEncoding
+	}
+	count, err := strconv.ParseUint(countText, 10, 64)
+	if err != nil {
+		return errInvalidEncoding
+	}
+	if version == encodingVersion {
+		order := BitOrder(-1)
+		for o, name := range orderNames {
+			if fields[3] == name {
+				order = o
+			}
+		}
+		clocks, err := strconv.ParseUint(fields[4], 10, 64)
+		if err != nil {
+			return errInvalidEncoding
+		}
+		if err := t.setOptions(order, clocks); err != nil {
+			return err
+		}
+	}
+	t.loadState(state)
+	t.count = count
+	return nil
+}
+
+// appendState appends the 288 cells of the state to b, cell 1 is the MSB of the first byte.
+func (t *Trivium) appendState(b []byte) []byte {
+	for i := 0; i < len(t.state)-1; i++ {
+		b = binary.BigEndian.AppendUint64(b, t.state[i])
+	}
+	// only the upper half of the last word holds cells 257 to 288
+	return binary.BigEndian.AppendUint32(b, uint32(t.state[len(t.state)-1]>>32))
+}
+
+// loadState sets the 288 cells of the state from b in the order written by appendState.
+func (t *Trivium) loadState(b []byte) {
+	for i := 0; i < len(t.state)-1; i++ {
+		t.state[i] = binary.BigEndian.Uint64(b[i<<3:])
+	}
+	t.state[len(t.state)-1] = uint64(binary.BigEndian.Uint32(b[(len(t.state)-1)<<3:])) << 32
+}
@@ -0,0 +1,196 @@
+package trivium
+
+import (
+	"bytes"
+	"encoding"
+	"strings"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Trivium)(nil)
+	_ encoding.BinaryUnmarshaler = (*Trivium)(nil)
+	_ encoding.TextMarshaler     = (*Trivium)(nil)
+	_ encoding.TextUnmarshaler   = (*Trivium)(nil)
+)
+
+func TestTriviumClone(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	trivium := NewTrivium(key, iv)
+	trivium.NextBits(13)
+	clone := trivium.Clone()
+	want := make([]byte, 100)
+	got := make([]byte, 100)
+	trivium.KeyStream(want)
+	clone.KeyStream(got)
+	if !bytes.Equal(got, want) {
+		t.Errorf("clone key stream doesn't match the original")
+	}
+	if clone.Count() != trivium.Count() {
+		t.Errorf("clone count %d != %d", clone.Count(), trivium.Count())
+	}
+}
+
+func TestTriviumCount(t *testing.T) {
+	var key, iv [KeyLength]byte
+	trivium := NewTrivium(key, iv)
+	if trivium.Count() != 0 {
+		t.Errorf("new Trivium count %d != 0", trivium.Count())
+	}
+	trivium.NextBit()
+	trivium.NextBits(7)
+	trivium.NextByte()
+	trivium.NextBytes(3)
+	trivium.NextUint64()
+	trivium.KeyStream(make([]byte, 11))
+	if want := uint64(1 + 7 + 8 + 24 + 64 + 88); trivium.Count() != want {
+		t.Errorf("count %d != %d", trivium.Count(), want)
+	}
+}
+
+func TestTriviumMarshal(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	for _, skip := range []uint{1, 29, 64} {
+		trivium := NewTrivium(key, iv)
+		trivium.NextBits(skip)
+
+		binaryState, err := trivium.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		textState, err := trivium.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromBinary, fromText Trivium
+		if err := fromBinary.UnmarshalBinary(binaryState); err != nil {
+			t.Fatal(err)
+		}
+		if err := fromText.UnmarshalText(textState); err != nil {
+			t.Fatal(err)
+		}
+
+		want := make([]byte, 100)
+		trivium.KeyStream(want)
+		for name, restored := range map[string]*Trivium{"binary": &fromBinary, "text": &fromText} {
+			if restored.Count() != uint64(skip) {
+				t.Errorf("%s: restored count %d != %d", name, restored.Count(), skip)
+			}
+			got := make([]byte, 100)
+			restored.KeyStream(got)
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: restored key stream after %d bits doesn't match", name, skip)
+			}
+		}
+	}
+}
+
+func TestTriviumMarshalOptions(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	trivium, err := NewTriviumWithOptions(key[:], iv[:], WithBitOrder(MSBFirst), WithInitClocks(672))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trivium.NextBits(29)
+	binaryState, _ := trivium.MarshalBinary()
+	textState, _ := trivium.MarshalText()
+	if !strings.HasSuffix(string(textState), ":msb:672") {
+		t.Errorf("MarshalText %s doesn't end with the bit order and clocks", textState)
+	}
+	var fromBinary, fromText Trivium
+	if err := fromBinary.UnmarshalBinary(binaryState); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromText.UnmarshalText(textState); err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, 100)
+	trivium.Clone().KeyStream(want)
+	for name, restored := range map[string]*Trivium{"binary": &fromBinary, "text": &fromText} {
+		if restored.msbFirst != trivium.msbFirst || restored.initClocks() != trivium.initClocks() {
+			t.Errorf("%s: restored options don't match", name)
+		}
+		got := make([]byte, 100)
+		restored.KeyStream(got)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: restored MSBFirst key stream doesn't match", name)
+		}
+	}
+
+	// version 1 has no options, those of the receiver are kept
+	v1 := append([]byte("trv\x01"), binaryState[len(magic)+1:marshaledSizeV1]...)
+	v1Text := "trivium/1" + strings.TrimSuffix(string(textState[len("trivium/2"):]), ":msb:672")
+	lsb := NewTrivium(key, iv)
+	if err := lsb.UnmarshalBinary(v1); err != nil || lsb.msbFirst || lsb.initClocks() != defaultInitClocks {
+		t.Errorf("version 1 UnmarshalBinary changed the options, %v", err)
+	}
+	if err := lsb.UnmarshalText([]byte(v1Text)); err != nil || lsb.msbFirst || lsb.initClocks() != defaultInitClocks {
+		t.Errorf("version 1 UnmarshalText changed the options, %v", err)
+	}
+	if got, _ := lsb.MarshalBinary(); !bytes.Equal(got[len(magic)+1:marshaledSizeV1], v1[len(magic)+1:]) {
+		t.Errorf("version 1 state doesn't match")
+	}
+}
+
+func TestTriviumMarshalText(t *testing.T) {
+	var trivium Trivium
+	trivium.state[0] = 1 << 63           // cell 1
+	trivium.state[4] = uint64(7)<<32 | 1 // cells 286, 287 and 288 and a bit outside the state
+	trivium.count = 42
+	text, err := trivium.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "trivium/2:" +
+		"8000000000000000000000000000000000000000000000000000000000000000" + "00000007" + ":42:lsb:1152"
+	if string(text) != want {
+		t.Errorf("MarshalText got %s want %s", text, want)
+	}
+}
+
+func TestTriviumUnmarshalErrors(t *testing.T) {
+	var trivium Trivium
+	good, _ := NewTrivium([KeyLength]byte{}, [KeyLength]byte{}).MarshalBinary()
+	badVersion := append([]byte{}, good...)
+	badVersion[len(magic)]++
+	badOrder := append([]byte{}, good...)
+	badOrder[marshaledSizeV1] = 2
+	for _, c := range []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"empty", nil, errInvalidEncoding},
+		{"magic", []byte("xyz\x01"), errInvalidEncoding},
+		{"short", good[:len(good)-1], errInvalidEncoding},
+		{"version", badVersion, errUnknownVersion},
+		{"order", badOrder, errInvalidEncoding},
+		{"version 1 size", append([]byte("trv\x01"), good[len(magic)+1:]...), errInvalidEncoding},
+	} {
+		if err := trivium.UnmarshalBinary(c.b); err != c.want {
+			t.Errorf("UnmarshalBinary %s: got %v want %v", c.name, err, c.want)
+		}
+	}
+	goodText, _ := NewTrivium([KeyLength]byte{}, [KeyLength]byte{}).MarshalText()
+	for _, c := range []struct {
+		name string
+		text string
+		want error
+	}{
+		{"empty", "", errInvalidEncoding},
+		{"version", "trivium/3" + string(goodText[len("trivium/2"):]), errUnknownVersion},
+		{"order", strings.Replace(string(goodText), ":lsb:", ":xyz:", 1), errInvalidEncoding},
+		{"clocks", strings.TrimSuffix(string(goodText), "1152") + "4294967296", errInvalidEncoding},
+		{"version 1 fields", "trivium/1" + string(goodText[len("trivium/2"):]), errInvalidEncoding},
+		{"short", string(goodText[:len(goodText)-3]) + "x", errInvalidEncoding},
+		{"fields", string(goodText) + ":1", errInvalidEncoding},
+		{"hex", "trivium/1:" + string(bytes.Repeat([]byte("g"), 2*stateLength)) + ":0", errInvalidEncoding},
+	} {
+		if err := trivium.UnmarshalText([]byte(c.text)); err != c.want {
+			t.Errorf("UnmarshalText %s: got %v want %v", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package trivium
+
+import (
+	"crypto/cipher"
+	"unsafe"
+)
+
+// NewStream returns a cipher.Stream that encrypts or decrypts with the Trivium key stream
+// for the given key and initialization value (IV).  The result can be used directly with
+// cipher.StreamReader and cipher.StreamWriter.
+func NewStream(key, iv [KeyLength]byte) cipher.Stream {
+	return NewTrivium(key, iv)
+}
+
+// XORKeyStream XORs each byte in src with a byte from the key stream and stores the result in dst.
+// dst and src must overlap entirely or not at all and len(dst) must be at least len(src).
+// Successive calls continue the key stream where the previous call left off, so a message
+// may be processed in pieces of any size.
+func (t *Trivium) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("trivium: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("trivium: invalid buffer overlap")
+	}
+	if t.msbFirst {
+		t.xorKeyStreamMSBFirst(dst, src)
+		return
+	}
+	// process a whole word of key stream at a time, bytes are consumed LSB first like NextBytes
+	words := len(src) &^ 7
+	xorKeyStreamBlocks(t, dst, src[:words])
+	dst, src = dst[words:], src[words:]
+	if len(src) > 0 {
+		word := t.NextBits(uint(len(src)) << 3)
+		for i := range src {
+			dst[i] = src[i] ^ byte(word>>(uint(i)<<3))
+		}
+	}
+}
+
+// xorKeyStreamMSBFirst XORs src with the key stream bytes packed MSB first, a buffer at a time.
+func (t *Trivium) xorKeyStreamMSBFirst(dst, src []byte) {
+	var buf [512]byte
+	for len(src) > 0 {
+		n := min(len(src), len(buf))
+		t.KeyStream(buf[:n])
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ buf[i]
+		}
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// inexactOverlap reports whether x and y share memory at any non-corresponding index,
+// which would make the in-place processing of XORKeyStream clobber unread input.
+func inexactOverlap(x, y []byte) bool {
+	if len(x) == 0 || len(y) == 0 || &x[0] == &y[0] {
+		return false
+	}
+	return uintptr(unsafe.Pointer(&x[0])) <= uintptr(unsafe.Pointer(&y[len(y)-1])) &&
+		uintptr(unsafe.Pointer(&y[0])) <= uintptr(unsafe.Pointer(&x[len(x)-1]))
+}
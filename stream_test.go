@@ -0,0 +1,101 @@
+package trivium
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"io"
+	"testing"
+)
+
+var _ cipher.Stream = (*Trivium)(nil)
+
+func TestXORKeyStream(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	var totalBytes = 1000
+	src := make([]byte, totalBytes)
+	for i := range src {
+		src[i] = byte(i * 7)
+	}
+	trivium := NewTrivium(key, iv)
+	want := make([]byte, totalBytes)
+	for i := range want {
+		want[i] = src[i] ^ trivium.NextByte()
+	}
+	// the key stream must continue seamlessly across calls of any size
+	for chunk := 1; chunk <= 17; chunk++ {
+		stream := NewStream(key, iv)
+		got := make([]byte, totalBytes)
+		for i := 0; i < totalBytes; i += chunk {
+			end := i + chunk
+			if end > totalBytes {
+				end = totalBytes
+			}
+			stream.XORKeyStream(got[i:end], src[i:end])
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("XORKeyStream in chunks of %d doesn't match NextByte", chunk)
+		}
+	}
+	// in place
+	got := append([]byte{}, src...)
+	NewStream(key, iv).XORKeyStream(got, got)
+	if !bytes.Equal(got, want) {
+		t.Errorf("in place XORKeyStream doesn't match NextByte")
+	}
+}
+
+func TestXORKeyStreamPanics(t *testing.T) {
+	var key, iv [KeyLength]byte
+	buf := make([]byte, 64)
+	cases := []struct {
+		name     string
+		dst, src []byte
+	}{
+		{"short dst", buf[:10], buf[10:30]},
+		{"overlap", buf[1:33], buf[0:32]},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected XORKeyStream to panic", c.name)
+				}
+			}()
+			NewStream(key, iv).XORKeyStream(c.dst, c.src)
+		}()
+	}
+}
+
+func TestStreamReaderWriter(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	plaintext := bytes.Repeat([]byte("trivium stream "), 100)
+
+	var ciphertext bytes.Buffer
+	writer := cipher.StreamWriter{S: NewStream(key, iv), W: &ciphertext}
+	if _, err := writer.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	reader := cipher.StreamReader{S: NewStream(key, iv), R: &ciphertext}
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("StreamReader didn't decrypt StreamWriter output")
+	}
+}
+
+func BenchmarkXORKeyStream(b *testing.B) {
+	var key = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var IV = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var stream = NewStream(key, IV)
+	buf := make([]byte, 1024)
+
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stream.XORKeyStream(buf, buf)
+	}
+}
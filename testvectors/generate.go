@@ -0,0 +1,155 @@
+package testvectors
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	labelWidth = 28 // width the field names are right aligned to
+	lineBytes  = 16 // bytes of hex per line
+)
+
+var (
+	// ShortStream are the chunks of the 512-byte key stream of the eSTREAM sets 1, 2, 3 and 5.
+	ShortStream = []int{0, 192, 256, 448}
+	// LongStream are the chunks of the 128 KiB key stream of the eSTREAM sets 4 and 6.
+	LongStream = []int{0, 65472, 65536, 131008}
+)
+
+// NewVector returns a vector for key and iv with an empty BlockSize chunk at each start, to be
+// filled by Generate.  The digest covers the key stream up to the end of the last chunk.
+func NewVector(set, number int, key, iv []byte, starts []int) *Vector {
+	v := &Vector{Set: set, Number: number, Key: key, IV: iv}
+	for _, start := range starts {
+		v.Chunks = append(v.Chunks, Chunk{Start: start, Data: make([]byte, BlockSize)})
+	}
+	return v
+}
+
+// ESTREAMSets returns the six eSTREAM test vector sets for a primitive with the given key
+// and IV sizes, without the key stream, see Generate.  Like the published files only every
+// ninth vector of sets 1, 2, 3 and 5 is included.
+//
+//	Set 1: a single bit of the key set, zero IV
+//	Set 2: every key byte equal to the vector number, zero IV
+//	Set 3: key byte j equal to the vector number plus j, zero IV
+//	Set 4: key byte j equal to 5 times the vector number plus 0x53*j, zero IV, 128 KiB of key stream
+//	Set 5: zero key, a single bit of the IV set
+//	Set 6: the keys of set 4, IV byte j equal to 9 times the vector number plus 13 plus 0x67*j
+func ESTREAMSets(primitive, profile string, keyBits, ivBits int) *File {
+	f := &File{
+		Primitive: primitive,
+		Profile:   profile,
+		KeyBits:   keyBits,
+		IVBits:    ivBits,
+		Notes:     map[int][]string{1: {"(stream is generated by encrypting 512 zero bytes)"}},
+	}
+	keyBytes, ivBytes := keyBits/8, ivBits/8
+	pattern := func(n int, f func(j int) int) []byte {
+		b := make([]byte, n)
+		for j := range b {
+			b[j] = byte(f(j))
+		}
+		return b
+	}
+	bit := func(n, i int) []byte {
+		b := make([]byte, n)
+		b[i/8] = 0x80 >> (i % 8)
+		return b
+	}
+	add := func(set, number int, key, iv []byte, starts []int) {
+		f.Vectors = append(f.Vectors, NewVector(set, number, key, iv, starts))
+	}
+	for i := 0; i < keyBits; i += 9 {
+		add(1, i, bit(keyBytes, i), make([]byte, ivBytes), ShortStream)
+	}
+	for i := 0; i < 256; i += 9 {
+		add(2, i, pattern(keyBytes, func(j int) int { return i }), make([]byte, ivBytes), ShortStream)
+	}
+	for i := 0; i < 256; i += 9 {
+		add(3, i, pattern(keyBytes, func(j int) int { return i + j }), make([]byte, ivBytes), ShortStream)
+	}
+	set4Key := func(i int) []byte { return pattern(keyBytes, func(j int) int { return 5*i + 0x53*j }) }
+	for i := 0; i < 4; i++ {
+		add(4, i, set4Key(i), make([]byte, ivBytes), LongStream)
+	}
+	for i := 0; i < ivBits; i += 9 {
+		add(5, i, make([]byte, keyBytes), bit(ivBytes, i), ShortStream)
+	}
+	for i := 0; i < 4; i++ {
+		add(6, i, set4Key(i), pattern(ivBytes, func(j int) int { return 9*i + 13 + 0x67*j }), LongStream)
+	}
+	return f
+}
+
+// Generate fills every chunk and the digest of v from keyStream.
+func (v *Vector) Generate(keyStream KeyStreamFunc) error {
+	stream := make([]byte, v.StreamLength())
+	if err := keyStream(v.Key, v.IV, stream); err != nil {
+		return err
+	}
+	for i, c := range v.Chunks {
+		v.Chunks[i].Data = append(c.Data[:0], stream[c.Start:c.Start+len(c.Data)]...)
+	}
+	v.Digest = Digest(stream)
+	return nil
+}
+
+// Generate fills every vector of f from keyStream.
+func (f *File) Generate(keyStream KeyStreamFunc) error {
+	for _, v := range f.Vectors {
+		if err := v.Generate(keyStream); err != nil {
+			return fmt.Errorf("testvectors: %s: %w", v.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Write writes f in the eSTREAM format, so that writing a parsed file reproduces it exactly.
+func (f *File) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	underline := func(s string) {
+		fmt.Fprintf(bw, "%s\n%s\n", s, strings.Repeat("=", len(s)))
+	}
+	bw.WriteString("\n")
+	underline("Primitive Name: " + f.Primitive)
+	fmt.Fprintf(bw, "Profile: %s\nKey size: %d bits\nIV size: %d bits\n\n", f.Profile, f.KeyBits, f.IVBits)
+	set := -1
+	for _, v := range f.Vectors {
+		if v.Set != set {
+			set = v.Set
+			underline(fmt.Sprintf("Test vectors -- set %d", set))
+			bw.WriteString("\n")
+			for _, note := range f.Notes[set] {
+				bw.WriteString(note + "\n\n")
+			}
+		}
+		fmt.Fprintf(bw, "%s:\n", v.Name())
+		writeField(bw, "key", v.Key)
+		writeField(bw, "IV", v.IV)
+		for _, c := range v.Chunks {
+			writeField(bw, fmt.Sprintf("stream[%d..%d]", c.Start, c.Start+len(c.Data)-1), c.Data)
+		}
+		if v.Digest != nil {
+			writeField(bw, "xor-digest", v.Digest)
+		}
+		bw.WriteString("\n")
+	}
+	bw.WriteString("\n\nEnd of test vectors\n")
+	return bw.Flush()
+}
+
+// writeField writes a field as upper case hex, lineBytes to a line, aligned under the first.
+func writeField(w *bufio.Writer, name string, b []byte) {
+	label := fmt.Sprintf("%*s = ", labelWidth, name)
+	for i := 0; i == 0 || i < len(b); i += lineBytes {
+		w.WriteString(label)
+		w.WriteString(strings.ToUpper(hex.EncodeToString(b[i:min(i+lineBytes, len(b))])))
+		w.WriteString("\n")
+		label = strings.Repeat(" ", len(label))
+	}
+}
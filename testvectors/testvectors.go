@@ -0,0 +1,330 @@
+/*
+Package testvectors parses and verifies stream cipher test vectors in the format of the eSTREAM
+project, such as trivium-80.80.test-vectors.
+
+A file has a header naming the primitive, its profile and the key and IV sizes, followed by
+sets of vectors.  Each vector gives the key and IV, some 64-byte ranges of the key stream and
+the xor-digest, the XOR of every 64-byte block of the whole key stream:
+
+	Set 1, vector#  0:
+	                         key = 80000000000000000000
+	                          IV = 00000000000000000000
+	               stream[0..63] = 38EB86FF730D7A9CAF8DF13A4420540D
+	                               ...
+	                  xor-digest = 7AE3A4B53355061766122E04391EA1E6
+	                               ...
+
+The package does not depend on any cipher, the key stream to verify against is supplied by a
+KeyStreamFunc.
+*/
+package testvectors
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BlockSize is the number of key stream bytes in each block XORed into the digest.
+const BlockSize = 64
+
+// File is a parsed test vector file.
+type File struct {
+	Primitive string // the primitive name, TRIVIUM
+	Profile   string // the eSTREAM profile, ___H3
+	KeyBits   int    // key size in bits
+	IVBits    int    // IV size in bits
+	Vectors   []*Vector
+	Notes     map[int][]string // comments after the heading of each set, such as "(stream is ...)"
+}
+
+// Vector is a single test vector, the key and IV are the bytes as written in the file.
+type Vector struct {
+	Set    int     // the set the vector belongs to
+	Number int     // the vector number within the set
+	Line   int     // the line of the file the vector starts on
+	Key    []byte  // the key as written
+	IV     []byte  // the IV as written
+	Chunks []Chunk // the ranges of the key stream given
+	Digest []byte  // XOR of all the BlockSize blocks of the key stream
+}
+
+// Chunk is a range of the key stream starting at byte Start.
+type Chunk struct {
+	Start int
+	Data  []byte
+}
+
+// StreamLength returns the number of key stream bytes covered by the digest, the end of the
+// last chunk rounded up to a whole block, 512 bytes or 128 KiB for the eSTREAM sets.
+func (v *Vector) StreamLength() int {
+	n := 0
+	for _, c := range v.Chunks {
+		n = max(n, c.Start+len(c.Data))
+	}
+	return (n + BlockSize - 1) / BlockSize * BlockSize
+}
+
+// Name returns the name of the vector as in the file, for example "Set 1, vector#  0".
+func (v *Vector) Name() string {
+	return fmt.Sprintf("Set %d, vector#%3d", v.Set, v.Number)
+}
+
+var (
+	setRe    = regexp.MustCompile(`^Test vectors -- set (\d+)$`)
+	vectorRe = regexp.MustCompile(`^Set (\d+), vector#\s*(\d+):$`)
+	fieldRe  = regexp.MustCompile(`^(key|IV|stream\[(\d+)\.\.(\d+)\]|xor-digest) = ([0-9A-Fa-f]+)$`)
+	hexRe    = regexp.MustCompile(`^[0-9A-Fa-f]+$`)
+	headerRe = regexp.MustCompile(`^(Primitive Name|Profile|Key size|IV size): (.*)$`)
+)
+
+// errNoVector is returned for a field outside of any vector.
+var errNoVector = errors.New("field before the first vector")
+
+// Parse reads a test vector file in the eSTREAM format.
+func Parse(r io.Reader) (*File, error) {
+	p := parser{file: new(File)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.line++
+		if err := p.parseLine(strings.TrimSpace(scanner.Text())); err != nil {
+			return nil, fmt.Errorf("testvectors: line %d: %w", p.line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("testvectors: %w", err)
+	}
+	if err := p.endVector(); err != nil {
+		return nil, fmt.Errorf("testvectors: line %d: %w", p.line, err)
+	}
+	if !p.end {
+		return nil, errors.New("testvectors: missing \"End of test vectors\"")
+	}
+	return p.file, nil
+}
+
+// parser holds the state of Parse between lines.
+type parser struct {
+	file   *File
+	line   int
+	set    int
+	vector *Vector
+	field  *[]byte // the hex field continued by lines of only hex digits
+	want   int     // the length the current stream chunk must have, zero for other fields
+	end    bool    // the end marker has been seen
+}
+
+func (p *parser) parseLine(line string) error {
+	if p.end && line != "" {
+		return errors.New("text after the end of the test vectors")
+	}
+	switch {
+	case line == "" || strings.Trim(line, "=") == "":
+		// blank lines and underlines
+		return p.endField()
+	case strings.HasPrefix(line, "("):
+		// comments such as "(stream is generated by encrypting 512 zero bytes)"
+		if p.file.Notes == nil {
+			p.file.Notes = make(map[int][]string)
+		}
+		p.file.Notes[p.set] = append(p.file.Notes[p.set], line)
+		return p.endField()
+	case line == "End of test vectors":
+		p.end = true
+		return p.endVector()
+	case hexRe.MatchString(line) && p.field != nil:
+		b, err := hex.DecodeString(line)
+		if err != nil {
+			return err
+		}
+		*p.field = append(*p.field, b...)
+		return nil
+	}
+	if m := headerRe.FindStringSubmatch(line); m != nil {
+		return p.header(m[1], m[2])
+	}
+	if m := setRe.FindStringSubmatch(line); m != nil {
+		if err := p.endVector(); err != nil {
+			return err
+		}
+		p.set, _ = strconv.Atoi(m[1])
+		return nil
+	}
+	if m := vectorRe.FindStringSubmatch(line); m != nil {
+		if err := p.endVector(); err != nil {
+			return err
+		}
+		set, _ := strconv.Atoi(m[1])
+		if set != p.set {
+			return fmt.Errorf("vector of set %d in set %d", set, p.set)
+		}
+		number, _ := strconv.Atoi(m[2])
+		p.vector = &Vector{Set: set, Number: number, Line: p.line}
+		return nil
+	}
+	if m := fieldRe.FindStringSubmatch(line); m != nil {
+		return p.parseField(m[1], m[2], m[3], m[4])
+	}
+	return fmt.Errorf("unexpected %q", line)
+}
+
+// header records a line of the header.
+func (p *parser) header(name, value string) error {
+	var err error
+	switch name {
+	case "Primitive Name":
+		p.file.Primitive = value
+	case "Profile":
+		p.file.Profile = value
+	case "Key size":
+		p.file.KeyBits, err = bits(value)
+	case "IV size":
+		p.file.IVBits, err = bits(value)
+	}
+	return err
+}
+
+// bits parses a size such as "80 bits".
+func bits(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSuffix(s, " bits"))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n, nil
+}
+
+// parseField starts the key, IV, a stream chunk or the digest of the current vector.
+func (p *parser) parseField(name, start, end, value string) error {
+	if err := p.endField(); err != nil {
+		return err
+	}
+	if p.vector == nil {
+		return errNoVector
+	}
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return err
+	}
+	v := p.vector
+	switch name {
+	case "key":
+		v.Key = b
+		p.field = &v.Key
+	case "IV":
+		v.IV = b
+		p.field = &v.IV
+	case "xor-digest":
+		v.Digest = b
+		p.field = &v.Digest
+	default:
+		first, err1 := strconv.Atoi(start)
+		last, err2 := strconv.Atoi(end)
+		if err1 != nil || err2 != nil || last < first {
+			return fmt.Errorf("invalid stream range %s..%s", start, end)
+		}
+		v.Chunks = append(v.Chunks, Chunk{Start: first, Data: b})
+		p.field = &v.Chunks[len(v.Chunks)-1].Data
+		p.want = last - first + 1
+	}
+	return nil
+}
+
+// endField checks the length of the field that has just been completed.
+func (p *parser) endField() error {
+	if p.field != nil && p.want != 0 && len(*p.field) != p.want {
+		return fmt.Errorf("stream chunk has %d bytes, want %d", len(*p.field), p.want)
+	}
+	p.field, p.want = nil, 0
+	return nil
+}
+
+// endVector checks the current vector is complete and adds it to the file.
+func (p *parser) endVector() error {
+	if err := p.endField(); err != nil {
+		return err
+	}
+	v := p.vector
+	if v == nil {
+		return nil
+	}
+	p.vector = nil
+	switch {
+	case p.file.KeyBits != 0 && len(v.Key)*8 != p.file.KeyBits:
+		return fmt.Errorf("%s: %d-bit key, want %d bits", v.Name(), len(v.Key)*8, p.file.KeyBits)
+	case p.file.IVBits != 0 && len(v.IV)*8 != p.file.IVBits:
+		return fmt.Errorf("%s: %d-bit IV, want %d bits", v.Name(), len(v.IV)*8, p.file.IVBits)
+	case len(v.Chunks) == 0:
+		return fmt.Errorf("%s: no key stream", v.Name())
+	case v.Digest != nil && len(v.Digest) != BlockSize:
+		return fmt.Errorf("%s: %d-byte digest, want %d", v.Name(), len(v.Digest), BlockSize)
+	}
+	p.file.Vectors = append(p.file.Vectors, v)
+	return nil
+}
+
+// KeyStreamFunc fills stream with the key stream for key and iv, both as written in the file.
+type KeyStreamFunc func(key, iv, stream []byte) error
+
+// Result is the outcome of verifying one vector.
+type Result struct {
+	Vector *Vector
+	Err    error // nil if the vector passed
+}
+
+// Pass reports whether the vector passed.
+func (r Result) Pass() bool {
+	return r.Err == nil
+}
+
+// String returns the name of the vector followed by pass or the reason it failed.
+func (r Result) String() string {
+	if r.Err != nil {
+		return r.Vector.Name() + ": FAIL: " + r.Err.Error()
+	}
+	return r.Vector.Name() + ": pass"
+}
+
+// Verify generates the whole key stream of v, StreamLength bytes, and compares every chunk
+// and the digest.
+func (v *Vector) Verify(keyStream KeyStreamFunc) error {
+	stream := make([]byte, v.StreamLength())
+	if err := keyStream(v.Key, v.IV, stream); err != nil {
+		return err
+	}
+	for _, c := range v.Chunks {
+		if got := stream[c.Start : c.Start+len(c.Data)]; !bytes.Equal(got, c.Data) {
+			return fmt.Errorf("stream[%d..%d] = %X, want %X", c.Start, c.Start+len(c.Data)-1, got, c.Data)
+		}
+	}
+	if v.Digest != nil {
+		if got := Digest(stream); !bytes.Equal(got, v.Digest) {
+			return fmt.Errorf("xor-digest = %X, want %X", got, v.Digest)
+		}
+	}
+	return nil
+}
+
+// Verify checks every vector of f, returning a result per vector in the order of the file.
+func (f *File) Verify(keyStream KeyStreamFunc) []Result {
+	results := make([]Result, len(f.Vectors))
+	for i, v := range f.Vectors {
+		results[i] = Result{Vector: v, Err: v.Verify(keyStream)}
+	}
+	return results
+}
+
+// Digest returns the xor-digest of stream, the XOR of its BlockSize blocks.  A final partial
+// block is XORed into the start of the digest.
+func Digest(stream []byte) []byte {
+	digest := make([]byte, BlockSize)
+	for i, b := range stream {
+		digest[i%BlockSize] ^= b
+	}
+	return digest
+}
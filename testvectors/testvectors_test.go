@@ -0,0 +1,232 @@
+package testvectors
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const sample = `
+Primitive Name: TOY
+===================
+Profile: ___H3
+Key size: 16 bits
+IV size: 8 bits
+
+Test vectors -- set 1
+=====================
+
+(stream is a counter)
+
+Set 1, vector#  0:
+                         key = 0102
+                          IV = 03
+               stream[0..15] = 000102030405060708090A0B0C0D0E0F
+             stream[64..127] = 404142434445464748494A4B4C4D4E4F
+                               505152535455565758595A5B5C5D5E5F
+                               606162636465666768696A6B6C6D6E6F
+                               707172737475767778797A7B7C7D7E7F
+                  xor-digest = 40404040404040404040404040404040
+                               40404040404040404040404040404040
+                               40404040404040404040404040404040
+                               40404040404040404040404040404040
+
+Set 1, vector# 12:
+                         key = 0405
+                          IV = 06
+               stream[0..15] = 000102030405060708090A0B0C0D0E0F
+
+
+
+End of test vectors
+`
+
+// counter is a KeyStreamFunc whose key stream byte i is i for every key and IV.
+func counter(key, iv, stream []byte) error {
+	for i := range stream {
+		stream[i] = byte(i)
+	}
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Primitive != "TOY" || f.Profile != "___H3" || f.KeyBits != 16 || f.IVBits != 8 {
+		t.Errorf("header %q %q %d %d", f.Primitive, f.Profile, f.KeyBits, f.IVBits)
+	}
+	if len(f.Vectors) != 2 {
+		t.Fatalf("parsed %d vectors, want 2", len(f.Vectors))
+	}
+	v := f.Vectors[0]
+	if v.Set != 1 || v.Number != 0 || v.Line != 13 || !bytes.Equal(v.Key, []byte{1, 2}) || !bytes.Equal(v.IV, []byte{3}) {
+		t.Errorf("vector %+v", v)
+	}
+	if len(v.Chunks) != 2 || v.Chunks[1].Start != 64 || len(v.Chunks[1].Data) != 64 || v.Chunks[1].Data[63] != 0x7F {
+		t.Errorf("chunks %+v", v.Chunks)
+	}
+	if v.StreamLength() != 128 {
+		t.Errorf("StreamLength() = %d, want 128", v.StreamLength())
+	}
+	if v.Name() != "Set 1, vector#  0" || f.Vectors[1].Name() != "Set 1, vector# 12" {
+		t.Errorf("names %q and %q", v.Name(), f.Vectors[1].Name())
+	}
+	if f.Vectors[1].Digest != nil || f.Vectors[1].StreamLength() != 64 {
+		t.Errorf("vector without a digest %+v", f.Vectors[1])
+	}
+}
+
+func TestVerify(t *testing.T) {
+	f, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range f.Verify(counter) {
+		if !r.Pass() {
+			t.Error(r)
+		}
+		if want := r.Vector.Name() + ": pass"; r.String() != want {
+			t.Errorf("String() = %q, want %q", r.String(), want)
+		}
+	}
+	// a single wrong byte fails the chunk, or the digest if it is outside every chunk
+	for _, wrong := range []int{5, 40} {
+		results := f.Verify(func(key, iv, stream []byte) error {
+			counter(key, iv, stream)
+			stream[wrong] ^= 1
+			return nil
+		})
+		if results[0].Pass() {
+			t.Errorf("byte %d changed, the vector passed", wrong)
+		}
+	}
+	errStream := errors.New("no key stream")
+	results := f.Verify(func(key, iv, stream []byte) error { return errStream })
+	if !errors.Is(results[1].Err, errStream) || !strings.Contains(results[1].String(), "FAIL") {
+		t.Errorf("result %v, want the KeyStreamFunc error", results[1])
+	}
+}
+
+func TestDigest(t *testing.T) {
+	stream := make([]byte, 3*BlockSize)
+	counter(nil, nil, stream)
+	digest := Digest(stream)
+	for i, b := range digest {
+		if want := byte(i) ^ byte(i+64) ^ byte(i+128); b != want {
+			t.Fatalf("digest byte %d = %02X, want %02X", i, b, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	vector := "Test vectors -- set 1\nSet 1, vector#  0:\nkey = 0102\nIV = 03\n"
+	tests := []string{
+		"",                                   // no end marker
+		"key = 00\nEnd of test vectors",      // a field outside a vector
+		"Key size: many bits\n",              // invalid size
+		vector + "End of test vectors\nmore", // text after the end
+		vector + "stream[0..3] = 000102\nEnd of test vectors",                                // short chunk
+		vector + "stream[3..0] = 00\nEnd of test vectors",                                    // backwards range
+		vector + "End of test vectors",                                                       // no key stream
+		vector + "stream[0..0] = 00\nxor-digest = 00\nEnd of test vectors",                   // short digest
+		vector + "stream[0..0] = 00\nwhat is this\nEnd of test vectors",                      // unknown line
+		"Test vectors -- set 1\nSet 2, vector#  0:\nEnd of test vectors",                     // wrong set
+		"Key size: 8 bits\n" + vector + "stream[0..0] = 00\nEnd of test vectors",             // wrong key size
+		"IV size: 16 bits\n" + vector + "stream[0..0] = 00\nEnd of test vectors",             // wrong IV size
+		vector + "stream[0..1] = 0001\n0\nEnd of test vectors",                               // odd hex
+		"Test vectors -- set 1\nSet 1, vector#  0:\nkey = 0102\nIV = 0\nEnd of test vectors", // odd hex field
+	}
+	for i, test := range tests {
+		if _, err := Parse(strings.NewReader(test)); err == nil {
+			t.Errorf("case %d: Parse succeeded, want an error", i)
+		}
+	}
+}
+
+func TestParseESTREAM(t *testing.T) {
+	file, err := os.Open("../trivium-80.80.test-vectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	f, err := Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Primitive != "TRIVIUM" || f.KeyBits != 80 || f.IVBits != 80 {
+		t.Errorf("header %q %d %d", f.Primitive, f.KeyBits, f.IVBits)
+	}
+	sets := map[int]int{}
+	for _, v := range f.Vectors {
+		sets[v.Set]++
+		want := 512
+		if v.Set == 4 || v.Set == 6 {
+			want = 131072
+		}
+		if v.StreamLength() != want || len(v.Digest) != BlockSize || len(v.Chunks) != 4 {
+			t.Errorf("%s: %d chunks covering %d bytes with a %d-byte digest", v.Name(), len(v.Chunks), v.StreamLength(), len(v.Digest))
+		}
+	}
+	if len(sets) != 6 || len(f.Vectors) != 84 {
+		t.Errorf("parsed %d vectors in %d sets, want 84 in 6", len(f.Vectors), len(sets))
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	for _, name := range []string{"../trivium-80.80.test-vectors", "../selftest-80.80.test-vectors"} {
+		want, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := Parse(bytes.NewReader(want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got bytes.Buffer
+		if err := f.Write(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("%s: writing the parsed file does not reproduce it", name)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	f := ESTREAMSets("TOY", "___H3", 80, 80)
+	if err := f.Generate(counter); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range f.Verify(counter) {
+		if !r.Pass() {
+			t.Error(r)
+		}
+	}
+	// the vectors of every set match the published ones, whatever the key stream
+	file, err := os.Open("../trivium-80.80.test-vectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	published, err := Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Vectors) != len(published.Vectors) {
+		t.Fatalf("generated %d vectors, want %d", len(f.Vectors), len(published.Vectors))
+	}
+	for i, v := range f.Vectors {
+		p := published.Vectors[i]
+		if v.Name() != p.Name() || !bytes.Equal(v.Key, p.Key) || !bytes.Equal(v.IV, p.IV) || v.StreamLength() != p.StreamLength() {
+			t.Errorf("generated %s key %X IV %X, want %s key %X IV %X", v.Name(), v.Key, v.IV, p.Name(), p.Key, p.IV)
+		}
+	}
+	errStream := errors.New("no key stream")
+	if err := f.Generate(func(key, iv, stream []byte) error { return errStream }); !errors.Is(err, errStream) {
+		t.Errorf("Generate() = %v, want the KeyStreamFunc error", err)
+	}
+}
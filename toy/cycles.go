@@ -0,0 +1,149 @@
+package toy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxEnumerateBits is the largest state that Enumerate walks completely, 2^20 states.
+const MaxEnumerateBits = 20
+
+// maxPeriodBits is the longest cycle whose key stream KeyStreamPeriod will store.
+const maxPeriodBits = 1 << 30
+
+var errPeriodTooLong = errors.New("toy: the cycle is too long to store its key stream")
+
+// Cycle follows the states from start and returns the number of states before the first one
+// that repeats, tail, and the length of the cycle that is then repeated, period.  The number of
+// distinct states visited is tail+period.  The clocking of Trivium is invertible, so with the
+// Trivium structure every state is on a cycle and tail is zero, but other parameters may not be.
+// It uses Brent's algorithm, which only stores two states but takes time proportional to
+// tail+period, so it is only practical for small states, for anything near the 64-bit limit of
+// Step it will not finish in practice.
+func (p Params) Cycle(start uint64) (tail, period uint64, err error) {
+	if err := p.packed(); err != nil {
+		return 0, 0, err
+	}
+	// find the period by teleporting the tortoise to the hare at every power of two
+	power, period := uint64(1), uint64(1)
+	tortoise := start
+	hare, _ := p.Step(start)
+	for tortoise != hare {
+		if power == period {
+			tortoise = hare
+			power *= 2
+			period = 0
+		}
+		hare, _ = p.Step(hare)
+		period++
+	}
+	// the first repeat is where two walks period apart meet
+	tortoise, hare = start, start
+	for i := uint64(0); i < period; i++ {
+		hare, _ = p.Step(hare)
+	}
+	for tortoise != hare {
+		tortoise, _ = p.Step(tortoise)
+		hare, _ = p.Step(hare)
+		tail++
+	}
+	return tail, period, nil
+}
+
+// KeyStreamPeriod returns the eventual period of the key stream produced from start, the
+// smallest d such that the key stream repeats every d bits once the states are on their
+// cycle.  It divides the length of the cycle.  Like Cycle its time is proportional to the
+// period.
+func (p Params) KeyStreamPeriod(start uint64) (uint64, error) {
+	tail, period, err := p.Cycle(start)
+	if err != nil {
+		return 0, err
+	}
+	if period > maxPeriodBits {
+		return 0, errPeriodTooLong
+	}
+	state := start
+	for i := uint64(0); i < tail; i++ {
+		state, _ = p.Step(state)
+	}
+	z := make([]uint64, (period+63)>>6)
+	for i := uint64(0); i < period; i++ {
+		var bit uint64
+		state, bit = p.Step(state)
+		z[i>>6] |= bit << (i & 63)
+	}
+	bit := func(i uint64) uint64 { return z[i>>6] >> (i & 63) & 1 }
+	for d := uint64(1); d < period; d++ {
+		if period%d != 0 {
+			continue
+		}
+		repeats := true
+		for i := uint64(0); i < period-d && repeats; i++ {
+			repeats = bit(i) == bit(i+d)
+		}
+		if repeats {
+			return d, nil
+		}
+	}
+	return period, nil
+}
+
+// Summary is the structure of the complete state graph of a small cipher.
+type Summary struct {
+	States      uint64            // number of states, 2^StateBits
+	CycleStates uint64            // number of states that lie on a cycle
+	Cycles      map[uint64]uint64 // number of cycles of each length
+	Longest     uint64            // length of the longest cycle
+}
+
+// Permutation reports whether every state lies on a cycle, so the clocking is invertible.
+func (s *Summary) Permutation() bool {
+	return s.CycleStates == s.States
+}
+
+// Enumerate clocks every state of p once and returns the cycle structure of the state graph.
+// p must have at most MaxEnumerateBits cells.
+func (p Params) Enumerate() (*Summary, error) {
+	if err := p.packed(); err != nil {
+		return nil, err
+	}
+	if bits := p.StateBits(); bits > MaxEnumerateBits {
+		return nil, fmt.Errorf("toy: a %d-bit state is too large to enumerate, at most %d bits", bits, MaxEnumerateBits)
+	}
+	states := uint64(1) << p.StateBits()
+	s := &Summary{States: states, Cycles: make(map[uint64]uint64)}
+	// walk[x] is the walk that first visited state x, starting from 1, and pos[x] its step in that walk
+	walk := make([]uint32, states)
+	pos := make([]uint32, states)
+	id := uint32(0)
+	for start := uint64(0); start < states; start++ {
+		if walk[start] != 0 {
+			continue
+		}
+		id++
+		x, i := start, uint32(0)
+		for walk[x] == 0 {
+			walk[x], pos[x] = id, i
+			x, _ = p.Step(x)
+			i++
+		}
+		if walk[x] == id { // the walk closed on itself
+			length := uint64(i - pos[x])
+			s.Cycles[length]++
+			s.CycleStates += length
+			s.Longest = max(s.Longest, length)
+		}
+	}
+	return s, nil
+}
+
+// packed checks that p is valid and its state fits the packed representation of Step.
+func (p Params) packed() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.StateBits() > 64 {
+		return errPackedState
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package toy
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleParams_Enumerate() {
+	s, err := tiny.Enumerate()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("states:", s.States)
+	fmt.Println("permutation:", s.Permutation())
+	fmt.Println("longest cycle:", s.Longest)
+	// Output:
+	// states: 4096
+	// permutation: true
+	// longest cycle: 2818
+}
+
+func TestEnumerate(t *testing.T) {
+	s, err := tiny.Enumerate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.States != 1<<12 {
+		t.Errorf("States = %d, want %d", s.States, 1<<12)
+	}
+	// the Trivium structure is invertible, every state is on exactly one cycle
+	if !s.Permutation() {
+		t.Errorf("%d of %d states on cycles, want all", s.CycleStates, s.States)
+	}
+	var total uint64
+	for length, count := range s.Cycles {
+		total += length * count
+		if length > s.Longest {
+			t.Errorf("cycle of length %d is longer than Longest %d", length, s.Longest)
+		}
+	}
+	if total != s.CycleStates {
+		t.Errorf("cycles cover %d states, want %d", total, s.CycleStates)
+	}
+	// every state's cycle found by Cycle must be one of the enumerated lengths
+	for state := uint64(0); state < s.States; state += 37 {
+		tail, period, err := tiny.Cycle(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tail != 0 || s.Cycles[period] == 0 {
+			t.Errorf("state %X has tail %d and period %d, not an enumerated cycle", state, tail, period)
+		}
+	}
+}
+
+func TestCycleTail(t *testing.T) {
+	// feeding the last cell of the next register makes the clocking lose information
+	p := Params{Registers: []Register{
+		{Length: 3, Tap: 1, AND: [2]int{1, 2}, Feed: 4},
+		{Length: 4, Tap: 2, AND: [2]int{2, 3}, Feed: 3},
+	}}
+	s, err := p.Enumerate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Permutation() {
+		t.Errorf("every state is on a cycle, want some with a tail")
+	}
+	for state := uint64(0); state < s.States; state++ {
+		tail, period, err := p.Cycle(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// walking tail states must reach a state that returns to itself after period steps
+		x := state
+		for i := uint64(0); i < tail; i++ {
+			x, _ = p.Step(x)
+		}
+		y := x
+		for i := uint64(0); i < period; i++ {
+			y, _ = p.Step(y)
+			if y == x && i+1 < period {
+				t.Fatalf("state %X returns after %d steps, Cycle gave period %d", state, i+1, period)
+			}
+		}
+		if y != x {
+			t.Fatalf("state %X does not repeat after tail %d and period %d", state, tail, period)
+		}
+	}
+}
+
+func TestKeyStreamPeriod(t *testing.T) {
+	// state 305 is on a cycle of 4 states whose key stream repeats every 2 bits
+	states := []uint64{305}
+	for state := uint64(0); state < 1<<12; state += 101 {
+		states = append(states, state)
+	}
+	for _, state := range states {
+		_, period, err := tiny.Cycle(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := tiny.KeyStreamPeriod(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if period%d != 0 {
+			t.Errorf("state %X: key stream period %d does not divide the cycle length %d", state, d, period)
+		}
+		// the key stream repeats every d bits and no smaller divisor of the period works
+		var z []uint64
+		x := state
+		for i := uint64(0); i < 2*period; i++ {
+			var bit uint64
+			x, bit = tiny.Step(x)
+			z = append(z, bit)
+		}
+		repeats := func(e uint64) bool {
+			for i := uint64(0); i+e < uint64(len(z)); i++ {
+				if z[i] != z[i+e] {
+					return false
+				}
+			}
+			return true
+		}
+		if !repeats(d) {
+			t.Fatalf("state %X: key stream does not repeat every %d bits", state, d)
+		}
+		// the smallest period of the key stream divides every period, so checking the smaller
+		// divisors of the cycle length shows d is the smallest
+		for e := uint64(1); e < d; e++ {
+			if period%e == 0 && repeats(e) {
+				t.Errorf("state %X: key stream repeats every %d bits, less than %d", state, e, d)
+			}
+		}
+	}
+	if _, err := Trivium.KeyStreamPeriod(0); err == nil {
+		t.Errorf("KeyStreamPeriod of a 288-bit state did not fail")
+	}
+}
+
+func TestEnumerateTooLarge(t *testing.T) {
+	p := Params{Registers: []Register{{Length: MaxEnumerateBits + 1, Tap: 1, AND: [2]int{1, 2}, Feed: 3}}}
+	if _, err := p.Enumerate(); err == nil {
+		t.Errorf("Enumerate of a %d-bit state did not fail", MaxEnumerateBits+1)
+	}
+}
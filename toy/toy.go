@@ -0,0 +1,233 @@
+/*
+Package toy is a generalized Trivium with configurable register lengths and taps, for teaching
+and for studying the structure of the state graph.
+
+A cipher is a ring of shift registers.  Each register i computes
+
+	t_i = R_i[Tap] + R_i[Length]
+	z += t_i
+	t_i += R_i[AND[0]] * R_i[AND[1]] + R_(i+1)[Feed]
+
+and t_i is shifted into cell 1 of the next register R_(i+1), the last register feeding the first.
+Cells are numbered from 1.  The key is loaded into the first cells of the first register, the
+IV into the first cells of the second and the last Ones cells of the last register are set,
+which with the Trivium parameters is exactly the Trivium specification.
+
+Parameter sets with a state of at most 64 bits can be clocked on a packed state with Step, and
+small enough ones can be enumerated completely.  The toy ciphers are not secure.
+*/
+package toy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Register is one shift register of a Trivium-like cipher, the taps are cell numbers from 1.
+type Register struct {
+	Length int    // number of cells, the last cell is always tapped
+	Tap    int    // the other cell of the linear output tap
+	AND    [2]int // the two cells ANDed in the feedback
+	Feed   int    // the cell of the next register added to the feedback
+}
+
+// Params describe a Trivium-like cipher.
+type Params struct {
+	Registers  []Register
+	KeyBits    int // key bits loaded into the first cells of the first register
+	IVBits     int // IV bits loaded into the first cells of the second register
+	Ones       int // number of cells set at the end of the last register
+	InitClocks int // clocks of warm-up before the key stream
+}
+
+var (
+	// Trivium are the parameters of Trivium, the cipher of the parent package.
+	Trivium = Params{
+		Registers: []Register{
+			{Length: 93, Tap: 66, AND: [2]int{91, 92}, Feed: 78},    // cells 1 to 93, s171 is B78
+			{Length: 84, Tap: 69, AND: [2]int{82, 83}, Feed: 87},    // cells 94 to 177, s264 is C87
+			{Length: 111, Tap: 66, AND: [2]int{109, 110}, Feed: 69}, // cells 178 to 288, s69 is A69
+		},
+		KeyBits:    80,
+		IVBits:     80,
+		Ones:       3,
+		InitClocks: 4 * 288,
+	}
+
+	// BiviumB are the parameters of Bivium-B, the first two registers of Trivium in a ring.
+	BiviumB = Params{
+		Registers: []Register{
+			{Length: 93, Tap: 66, AND: [2]int{91, 92}, Feed: 78},
+			{Length: 84, Tap: 69, AND: [2]int{82, 83}, Feed: 69},
+		},
+		KeyBits:    80,
+		IVBits:     80,
+		InitClocks: 4 * 177,
+	}
+)
+
+var errPackedState = errors.New("toy: the state is larger than 64 bits")
+
+// Validate reports whether the taps of p are all within their registers and the key, IV and
+// constant cells fit.
+func (p Params) Validate() error {
+	n := len(p.Registers)
+	if n == 0 {
+		return errors.New("toy: no registers")
+	}
+	for i, r := range p.Registers {
+		if r.Length < 1 {
+			return fmt.Errorf("toy: register %d has length %d", i, r.Length)
+		}
+		for _, cell := range []int{r.Tap, r.AND[0], r.AND[1]} {
+			if cell < 1 || cell > r.Length {
+				return fmt.Errorf("toy: register %d has a tap at cell %d outside 1 to %d", i, cell, r.Length)
+			}
+		}
+		if next := p.Registers[(i+1)%n]; r.Feed < 1 || r.Feed > next.Length {
+			return fmt.Errorf("toy: register %d feeds from cell %d outside 1 to %d", i, r.Feed, next.Length)
+		}
+	}
+	if p.KeyBits < 0 || p.KeyBits > p.Registers[0].Length {
+		return fmt.Errorf("toy: %d key bits do not fit the first register", p.KeyBits)
+	}
+	if p.IVBits < 0 || p.IVBits > p.Registers[1%n].Length || (n == 1 && p.IVBits > 0) {
+		return fmt.Errorf("toy: %d IV bits do not fit the second register", p.IVBits)
+	}
+	if p.Ones < 0 || p.Ones > p.Registers[n-1].Length {
+		return fmt.Errorf("toy: %d constant cells do not fit the last register", p.Ones)
+	}
+	if p.InitClocks < 0 {
+		return fmt.Errorf("toy: invalid number of initialization clocks %d", p.InitClocks)
+	}
+	return nil
+}
+
+// StateBits returns the number of cells in the state.
+func (p Params) StateBits() int {
+	n := 0
+	for _, r := range p.Registers {
+		n += r.Length
+	}
+	return n
+}
+
+// Cipher is a Trivium-like cipher clocked one bit at a time, with one byte per cell.
+type Cipher struct {
+	p     Params
+	regs  [][]byte // regs[i][j] is cell j+1 of register i
+	t     []byte   // the feedback of each register, kept to avoid allocating per clock
+	count uint64   // number of key stream bits produced since initialization
+}
+
+// New returns a cipher with parameters p initialized with key and IV.  Bit i of the key is
+// bit i%8 of key[i/8], in the order of NewTrivium, and key and iv must have exactly the bytes
+// needed for KeyBits and IVBits bits.
+func New(p Params, key, iv []byte) (*Cipher, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if len(key) != (p.KeyBits+7)>>3 || len(iv) != (p.IVBits+7)>>3 {
+		return nil, fmt.Errorf("toy: a %d-bit key and %d-bit IV must be %d and %d bytes", p.KeyBits, p.IVBits, (p.KeyBits+7)>>3, (p.IVBits+7)>>3)
+	}
+	c := &Cipher{p: p, t: make([]byte, len(p.Registers))}
+	c.regs = make([][]byte, len(p.Registers))
+	for i, r := range p.Registers {
+		c.regs[i] = make([]byte, r.Length)
+	}
+	for i := 0; i < p.KeyBits; i++ {
+		c.regs[0][i] = key[i>>3] >> (i & 7) & 1
+	}
+	for i := 0; i < p.IVBits; i++ {
+		c.regs[1][i] = iv[i>>3] >> (i & 7) & 1
+	}
+	last := c.regs[len(c.regs)-1]
+	for i := len(last) - p.Ones; i < len(last); i++ {
+		last[i] = 1
+	}
+	for i := 0; i < p.InitClocks; i++ {
+		c.NextBit()
+	}
+	c.count = 0 // the warm-up is not part of the key stream
+	return c, nil
+}
+
+// NextBit clocks the cipher once and returns the key stream bit.
+func (c *Cipher) NextBit() uint64 {
+	n := len(c.regs)
+	var z byte
+	for i, r := range c.p.Registers {
+		reg := c.regs[i]
+		t := reg[r.Tap-1] ^ reg[r.Length-1]
+		z ^= t
+		c.t[i] = t ^ reg[r.AND[0]-1]&reg[r.AND[1]-1] ^ c.regs[(i+1)%n][r.Feed-1]
+	}
+	for i, reg := range c.regs {
+		copy(reg[1:], reg)
+		reg[0] = c.t[(i+n-1)%n]
+	}
+	c.count++
+	return uint64(z)
+}
+
+// KeyStream fills buf with the next len(buf) bytes of key stream, the first bit produced is
+// the least significant bit of each byte as in the parent package.
+func (c *Cipher) KeyStream(buf []byte) {
+	for i := range buf {
+		var b byte
+		for j := 0; j < 8; j++ {
+			b |= byte(c.NextBit()) << j
+		}
+		buf[i] = b
+	}
+}
+
+// Count returns the number of key stream bits produced since c was initialized.
+func (c *Cipher) Count() uint64 {
+	return c.count
+}
+
+// Load returns the packed state after loading key and iv, before the warm-up, see Step.
+func (p Params) Load(key, iv []byte) (uint64, error) {
+	if p.StateBits() > 64 {
+		return 0, errPackedState
+	}
+	c, err := New(Params{Registers: p.Registers, KeyBits: p.KeyBits, IVBits: p.IVBits, Ones: p.Ones}, key, iv)
+	if err != nil {
+		return 0, err
+	}
+	var state uint64
+	offset := 0
+	for _, reg := range c.regs {
+		for j, cell := range reg {
+			state |= uint64(cell) << (offset + j)
+		}
+		offset += len(reg)
+	}
+	return state, nil
+}
+
+// Step clocks the packed state once, returning the next state and the key stream bit.  In
+// the packed state the registers follow each other from the least significant bit, cell j of
+// a register that starts at bit o is bit o+j-1.  p must be valid with at most 64 cells.
+func (p Params) Step(state uint64) (next, z uint64) {
+	var offsets [64]int
+	n := len(p.Registers)
+	for i := 1; i < n; i++ {
+		offsets[i] = offsets[i-1] + p.Registers[i-1].Length
+	}
+	cell := func(i, j int) uint64 { return state >> (offsets[i] + j - 1) & 1 }
+	var t [64]uint64
+	for i, r := range p.Registers {
+		t[i] = cell(i, r.Tap) ^ cell(i, r.Length)
+		z ^= t[i]
+		t[i] ^= cell(i, r.AND[0])&cell(i, r.AND[1]) ^ cell((i+1)%n, r.Feed)
+	}
+	for i, r := range p.Registers {
+		mask := ^uint64(0) >> (64 - r.Length)
+		reg := state >> offsets[i] & mask
+		reg = (reg<<1 | t[(i+n-1)%n]) & mask
+		next |= reg << offsets[i]
+	}
+	return next, z
+}
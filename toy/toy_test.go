@@ -0,0 +1,103 @@
+package toy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bmkessler/trivium"
+)
+
+// tiny is a three register cipher with a 12-bit state, small enough to enumerate quickly
+var tiny = Params{
+	Registers: []Register{
+		{Length: 4, Tap: 2, AND: [2]int{2, 3}, Feed: 2},
+		{Length: 3, Tap: 1, AND: [2]int{1, 2}, Feed: 3},
+		{Length: 5, Tap: 3, AND: [2]int{3, 4}, Feed: 2},
+	},
+	KeyBits:    3,
+	IVBits:     2,
+	Ones:       1,
+	InitClocks: 4 * 12,
+}
+
+func TestTriviumParams(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var iv = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	c, err := New(Trivium, key[:], iv[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 256)
+	c.KeyStream(got)
+	want := make([]byte, len(got))
+	trivium.NewTrivium(key, iv).KeyStream(want)
+	if !bytes.Equal(got, want) {
+		t.Errorf("Trivium parameters give key stream\n%X\nwant\n%X", got, want)
+	}
+	if c.Count() != uint64(len(got))<<3 {
+		t.Errorf("Count() = %d, want %d", c.Count(), len(got)<<3)
+	}
+
+	c, err = New(BiviumB, key[:], iv[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.KeyStream(got)
+	trivium.NewBiviumB(key, iv).KeyStream(want)
+	if !bytes.Equal(got, want) {
+		t.Errorf("Bivium-B parameters give key stream\n%X\nwant\n%X", got, want)
+	}
+}
+
+func TestStep(t *testing.T) {
+	// Step on the packed state must agree with the cipher clocked one cell per byte
+	for k := byte(0); k < 8; k++ {
+		for iv := byte(0); iv < 4; iv++ {
+			state, err := tiny.Load([]byte{k}, []byte{iv})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := New(Params{Registers: tiny.Registers, KeyBits: 3, IVBits: 2, Ones: 1}, []byte{k}, []byte{iv})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 100; i++ {
+				var z uint64
+				state, z = tiny.Step(state)
+				if want := c.NextBit(); z != want {
+					t.Fatalf("key %d IV %d: Step bit %d is %d, want %d", k, iv, i, z, want)
+				}
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, p := range []Params{Trivium, BiviumB, tiny} {
+		if err := p.Validate(); err != nil {
+			t.Errorf("Validate() = %v", err)
+		}
+	}
+	bad := []Params{
+		{},
+		{Registers: []Register{{Length: 0}}},
+		{Registers: []Register{{Length: 4, Tap: 5, AND: [2]int{1, 2}, Feed: 1}}},
+		{Registers: []Register{{Length: 4, Tap: 1, AND: [2]int{0, 2}, Feed: 1}}},
+		{Registers: []Register{{Length: 4, Tap: 1, AND: [2]int{1, 2}, Feed: 5}}},
+		{Registers: []Register{{Length: 4, Tap: 1, AND: [2]int{1, 2}, Feed: 1}}, KeyBits: 5},
+		{Registers: []Register{{Length: 4, Tap: 1, AND: [2]int{1, 2}, Feed: 1}}, IVBits: 1},
+		{Registers: []Register{{Length: 4, Tap: 1, AND: [2]int{1, 2}, Feed: 1}}, Ones: 5},
+		{Registers: []Register{{Length: 4, Tap: 1, AND: [2]int{1, 2}, Feed: 1}}, InitClocks: -1},
+	}
+	for i, p := range bad {
+		if err := p.Validate(); err == nil {
+			t.Errorf("case %d: Validate() = nil, want an error", i)
+		}
+	}
+	if _, err := New(tiny, []byte{0, 0}, []byte{0}); err == nil {
+		t.Errorf("New with a long key did not fail")
+	}
+	if _, err := Trivium.Load(make([]byte, 10), make([]byte, 10)); err == nil {
+		t.Errorf("Load of a 288-bit state did not fail")
+	}
+}
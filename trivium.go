@@ -11,12 +11,24 @@ The trivium specification is http://www.ecrypt.eu.org/stream/p3ciphers/trivium/t
 This is a straighforward implementation based on the specification using SWAR calculations
 to calculate up to 64 bits at a time.
 
+On amd64 KeyStream and XORKeyStream use scalar assembly for 64 bits at a time, and the bitsliced
+engine uses AVX2 when the CPU supports it and SSE2 otherwise.  Building with the purego tag uses
+the portable Go code on every architecture.
+
 */
 package trivium
 
+import (
+	"math/bits"
+	"slices"
+)
+
 // Trivium represents the 288-bit state of the Trivium cipher.
 type Trivium struct {
-	state [5]uint64
+	state     [5]uint64
+	count     uint64 // number of key stream bits produced since initialization
+	msbFirst  bool   // pack key stream bytes with the first bit as the MSB, see MSBFirst
+	extraInit int    // initialization clocks beyond defaultInitClocks, see WithInitClocks
 }
 
 const (
@@ -64,27 +76,52 @@ const (
 	sh178    = mask - (177 & mask)
 )
 
+// defaultInitClocks of warm-up before the key stream, as in the specification
+const defaultInitClocks = 4 * 288
+
 // NewTrivium returns a Trivium cipher initialized with key and initialization value (IV).
 // Both the key and IV are 80-bits (10 bytes).  The initialization processes the cipher for
 // 4*288 cycles to "warm-up" and attempt to eliminate and usable dependency on key and IV.
 func NewTrivium(key, iv [KeyLength]byte) *Trivium {
+	var trivium Trivium
+	trivium.Reset(key, iv)
+	return &trivium
+}
+
+// Reset re-initializes t in place with key and initialization value (IV), exactly as NewTrivium
+// but without allocating.  The 4*288 warm-up cycles are processed a word at a time.
+// The output bit order and the number of initialization clocks of t are kept, so a cipher
+// from NewTriviumWithOptions is reset with the same options, the key and IV are Raw.
+func (t *Trivium) Reset(key, iv [KeyLength]byte) {
+	t.load(key, iv)
+	t.warmUp(t.initClocks())
+}
+
+// initClocks returns the number of warm-up clocks processed by Reset.
+func (t *Trivium) initClocks() int {
+	return defaultInitClocks + t.extraInit
+}
+
+// load sets the state to the key, IV and constant cells before the warm-up.
+func (t *Trivium) load(key, iv [KeyLength]byte) {
 	var state [5]uint64
 
-	state[0] |= (uint64(reverseByte(key[0])) << 56) | (uint64(reverseByte(key[1])) << 48) | (uint64(reverseByte(key[2])) << 40) | (uint64(reverseByte(key[3])) << 32)
-	state[0] |= (uint64(reverseByte(key[4])) << 24) | (uint64(reverseByte(key[5])) << 16) | (uint64(reverseByte(key[6])) << 8) | uint64(reverseByte(key[7]))
-	state[1] |= (uint64(reverseByte(key[8])) << 56) | (uint64(reverseByte(key[9])) << 48)
-	state[1] |= (uint64(reverseByte(iv[4])) >> 5) | (uint64(reverseByte(iv[3])) << 3) | (uint64(reverseByte(iv[2])) << 11) | (uint64(reverseByte(iv[1])) << 19) | (uint64(reverseByte(iv[0])) << 27)
-	state[2] |= (uint64(reverseByte(iv[7])) << 35) | (uint64(reverseByte(iv[6])) << 43) | (uint64(reverseByte(iv[5])) << 51) | (uint64(reverseByte(iv[4])) << 59)
-	state[2] |= (uint64(reverseByte(iv[9])) << 19) | (uint64(reverseByte(iv[8])) << 27)
+	state[0] |= (uint64(bits.Reverse8(key[0])) << 56) | (uint64(bits.Reverse8(key[1])) << 48) | (uint64(bits.Reverse8(key[2])) << 40) | (uint64(bits.Reverse8(key[3])) << 32)
+	state[0] |= (uint64(bits.Reverse8(key[4])) << 24) | (uint64(bits.Reverse8(key[5])) << 16) | (uint64(bits.Reverse8(key[6])) << 8) | uint64(bits.Reverse8(key[7]))
+	state[1] |= (uint64(bits.Reverse8(key[8])) << 56) | (uint64(bits.Reverse8(key[9])) << 48)
+	state[1] |= (uint64(bits.Reverse8(iv[4])) >> 5) | (uint64(bits.Reverse8(iv[3])) << 3) | (uint64(bits.Reverse8(iv[2])) << 11) | (uint64(bits.Reverse8(iv[1])) << 19) | (uint64(bits.Reverse8(iv[0])) << 27)
+	state[2] |= (uint64(bits.Reverse8(iv[7])) << 35) | (uint64(bits.Reverse8(iv[6])) << 43) | (uint64(bits.Reverse8(iv[5])) << 51) | (uint64(bits.Reverse8(iv[4])) << 59)
+	state[2] |= (uint64(bits.Reverse8(iv[9])) << 19) | (uint64(bits.Reverse8(iv[8])) << 27)
 	// state[3] is initialized with all zeros
 	state[4] |= uint64(7) << 32
 
-	trivium := Trivium{state: state}
-	for i := 0; i < 4*288; i++ {
-		trivium.NextBit()
-	}
+	t.state = state
+}
 
-	return &trivium
+// warmUp processes the cipher for n cycles, discarding the output.
+func (t *Trivium) warmUp(n int) {
+	t.Discard(uint64(n))
+	t.count = 0 // the warm-up is not part of the key stream
 }
 
 // NextBit gets the next bit from the Trivium stream.
@@ -92,34 +129,52 @@ func (t *Trivium) NextBit() uint64 {
 	return t.NextBits(1)
 }
 
-// NextBits gets the next 1 to 63 bits from the Trivium stream.
+// NextBits gets the next 1 to 64 bits from the Trivium stream.
+// The first bit produced is the least significant bit of the result.
 func (t *Trivium) NextBits(n uint) uint64 {
-	var bitmask uint64 = (1 << n) - 1
+	z := nextBits(&t.state, n, 0, 0)
+	t.count += uint64(n)
+	return z
+}
+
+// NextUint64 gets the next 64 bits from the Trivium stream, the first bit produced is the
+// least significant bit.
+func (t *Trivium) NextUint64() uint64 {
+	z := nextUint64(&t.state, 0, 0)
+	t.count += wordSize
+	return z
+}
+
+// nextBits clocks the 288-bit state s n times, 1 to 64, and returns the output bits.
+// k is XORed into t3 and iv into the feedback of t1 at each clock, the extra terms of
+// Kreyvium, they are zero for Trivium.
+func nextBits(s *[5]uint64, n uint, k, iv uint64) uint64 {
+	var bitmask = ^uint64(0) >> (wordSize - n)
 	// get the taps
-	s66 := (t.state[i66] >> sh66) | (t.state[i66-1] << (wordSize - sh66))
-	s93 := (t.state[i93] >> sh93) | (t.state[i93-1] << (wordSize - sh93))
-	s162 := (t.state[i162] >> sh162) | (t.state[i162-1] << (wordSize - sh162))
-	s177 := (t.state[i177] >> sh177) | (t.state[i177-1] << (wordSize - sh177))
-	s243 := (t.state[i243] >> sh243) | (t.state[i243-1] << (wordSize - sh243))
-	s288 := (t.state[i288] >> sh288) | (t.state[i288-1] << (wordSize - sh288))
+	s66 := (s[i66] >> sh66) | (s[i66-1] << (wordSize - sh66))
+	s93 := (s[i93] >> sh93) | (s[i93-1] << (wordSize - sh93))
+	s162 := (s[i162] >> sh162) | (s[i162-1] << (wordSize - sh162))
+	s177 := (s[i177] >> sh177) | (s[i177-1] << (wordSize - sh177))
+	s243 := (s[i243] >> sh243) | (s[i243-1] << (wordSize - sh243))
+	s288 := (s[i288] >> sh288) | (s[i288-1] << (wordSize - sh288))
 
 	t1 := s66 ^ s93
 	t2 := s162 ^ s177
-	t3 := s243 ^ s288
+	t3 := s243 ^ s288 ^ k
 	// store the output
 	z := (t1 ^ t2 ^ t3) & bitmask
 	// process the taps
-	s91 := (t.state[i91] >> sh91) | (t.state[i91-1] << (wordSize - sh91))
-	s92 := (t.state[i92] >> sh92) | (t.state[i92-1] << (wordSize - sh92))
-	s171 := (t.state[i171] >> sh171) | (t.state[i171-1] << (wordSize - sh171))
-	s175 := (t.state[i175] >> sh175) | (t.state[i175-1] << (wordSize - sh175))
-	s176 := (t.state[i176] >> sh176) | (t.state[i176-1] << (wordSize - sh176))
-	s264 := (t.state[i264] >> sh264) | (t.state[i264-1] << (wordSize - sh264))
-	s286 := (t.state[i286] >> sh286) | (t.state[i286-1] << (wordSize - sh286))
-	s287 := (t.state[i287] >> sh287) | (t.state[i287-1] << (wordSize - sh287))
-	s69 := (t.state[i69] >> sh69) | (t.state[i69-1] << (wordSize - sh69))
-
-	t1 ^= ((s91 & s92) ^ s171)
+	s91 := (s[i91] >> sh91) | (s[i91-1] << (wordSize - sh91))
+	s92 := (s[i92] >> sh92) | (s[i92-1] << (wordSize - sh92))
+	s171 := (s[i171] >> sh171) | (s[i171-1] << (wordSize - sh171))
+	s175 := (s[i175] >> sh175) | (s[i175-1] << (wordSize - sh175))
+	s176 := (s[i176] >> sh176) | (s[i176-1] << (wordSize - sh176))
+	s264 := (s[i264] >> sh264) | (s[i264-1] << (wordSize - sh264))
+	s286 := (s[i286] >> sh286) | (s[i286-1] << (wordSize - sh286))
+	s287 := (s[i287] >> sh287) | (s[i287-1] << (wordSize - sh287))
+	s69 := (s[i69] >> sh69) | (s[i69-1] << (wordSize - sh69))
+
+	t1 ^= ((s91 & s92) ^ s171 ^ iv)
 	t2 ^= ((s175 & s176) ^ s264)
 	t3 ^= ((s286 & s287) ^ s69)
 	t1 &= bitmask
@@ -127,11 +182,11 @@ func (t *Trivium) NextBits(n uint) uint64 {
 	t3 &= bitmask
 
 	// rotate the state
-	t.state[4] = (t.state[4] >> n) | (t.state[3] << (wordSize - n))
-	t.state[3] = (t.state[3] >> n) | (t.state[2] << (wordSize - n))
-	t.state[2] = (t.state[2] >> n) | (t.state[1] << (wordSize - n))
-	t.state[1] = (t.state[1] >> n) | (t.state[0] << (wordSize - n))
-	t.state[0] = (t.state[0] >> n) | (t3 << (wordSize - n))
+	s[4] = (s[4] >> n) | (s[3] << (wordSize - n))
+	s[3] = (s[3] >> n) | (s[2] << (wordSize - n))
+	s[2] = (s[2] >> n) | (s[1] << (wordSize - n))
+	s[1] = (s[1] >> n) | (s[0] << (wordSize - n))
+	s[0] = (s[0] >> n) | (t3 << (wordSize - n))
 	// update the final values
 
 	n94 := 92 + n
@@ -141,43 +196,141 @@ func (t *Trivium) NextBits(n uint) uint64 {
 	ni178 := n178 >> lgWordSize
 	nsh178 := mask - (n178 & mask)
 
-	t.state[ni94] = t.state[ni94] &^ (bitmask << nsh94)
-	t.state[ni94] |= t1 << nsh94
+	s[ni94] = s[ni94] &^ (bitmask << nsh94)
+	s[ni94] |= t1 << nsh94
 	// need to handle overlap across word boundaries
-	t.state[i94] = t.state[i94] &^ (bitmask >> (wordSize - nsh94))
-	t.state[i94] |= t1 >> (wordSize - nsh94)
+	s[i94] = s[i94] &^ (bitmask >> (wordSize - nsh94))
+	s[i94] |= t1 >> (wordSize - nsh94)
 
-	t.state[ni178] = t.state[ni178] &^ (bitmask << nsh178)
-	t.state[ni178] |= t2 << nsh178
+	s[ni178] = s[ni178] &^ (bitmask << nsh178)
+	s[ni178] |= t2 << nsh178
 	// need to handle overlap across word boundaries
-	t.state[i178] = t.state[i178] &^ (bitmask >> (wordSize - nsh178))
-	t.state[i178] |= t2 >> (wordSize - nsh178)
+	s[i178] = s[i178] &^ (bitmask >> (wordSize - nsh178))
+	s[i178] |= t2 >> (wordSize - nsh178)
+
+	return z
+}
+
+// nextUint64 clocks the 288-bit state s 64 times like nextBits.  The tap positions are all at
+// least 64 cells from where the feedback is inserted, so a full word can be computed in a
+// single step.  The state rotates by exactly one word, so no masking is needed as in nextBits.
+func nextUint64(s *[5]uint64, k, iv uint64) uint64 {
+	// get the taps
+	s66 := (s[i66] >> sh66) | (s[i66-1] << (wordSize - sh66))
+	s93 := (s[i93] >> sh93) | (s[i93-1] << (wordSize - sh93))
+	s162 := (s[i162] >> sh162) | (s[i162-1] << (wordSize - sh162))
+	s177 := (s[i177] >> sh177) | (s[i177-1] << (wordSize - sh177))
+	s243 := (s[i243] >> sh243) | (s[i243-1] << (wordSize - sh243))
+	s288 := (s[i288] >> sh288) | (s[i288-1] << (wordSize - sh288))
+
+	t1 := s66 ^ s93
+	t2 := s162 ^ s177
+	t3 := s243 ^ s288 ^ k
+	// store the output
+	z := t1 ^ t2 ^ t3
+	// process the taps
+	s91 := (s[i91] >> sh91) | (s[i91-1] << (wordSize - sh91))
+	s92 := (s[i92] >> sh92) | (s[i92-1] << (wordSize - sh92))
+	s171 := (s[i171] >> sh171) | (s[i171-1] << (wordSize - sh171))
+	s175 := (s[i175] >> sh175) | (s[i175-1] << (wordSize - sh175))
+	s176 := (s[i176] >> sh176) | (s[i176-1] << (wordSize - sh176))
+	s264 := (s[i264] >> sh264) | (s[i264-1] << (wordSize - sh264))
+	s286 := (s[i286] >> sh286) | (s[i286-1] << (wordSize - sh286))
+	s287 := (s[i287] >> sh287) | (s[i287-1] << (wordSize - sh287))
+	s69 := (s[i69] >> sh69) | (s[i69-1] << (wordSize - sh69))
+
+	t1 ^= ((s91 & s92) ^ s171 ^ iv)
+	t2 ^= ((s175 & s176) ^ s264)
+	t3 ^= ((s286 & s287) ^ s69)
+
+	// rotate the state by a whole word
+	s[4] = s[3]
+	s[3] = s[2]
+	s[2] = s[1]
+	s[1] = s[0]
+	s[0] = t3
+	// update the final values, the new bits of t1 fill cells 94 to 94+63 and t2 178 to 178+63
+	const mask94 = ^uint64(0) >> (wordSize - sh94 - 1)   // cells 94 and after in word i94
+	const mask178 = ^uint64(0) >> (wordSize - sh178 - 1) // cells 178 and after in word i178
+	s[i94] = s[i94]&^mask94 | t1>>(wordSize-sh94-1)
+	s[i94+1] = s[i94+1]&mask94 | t1<<(sh94+1)
+	s[i178] = s[i178]&^mask178 | t2>>(wordSize-sh178-1)
+	s[i178+1] = s[i178+1]&mask178 | t2<<(sh178+1)
 
 	return z
 }
 
+// KeyStream fills buf with the next len(buf) bytes of key stream, in the same order as
+// repeated calls to NextByte.  Whole words are produced as by NextUint64.
+func (t *Trivium) KeyStream(buf []byte) {
+	words := len(buf) &^ 7
+	clear(buf[:words])
+	xorKeyStreamBlocks(t, buf[:words], buf[:words])
+	if tail := buf[words:]; len(tail) > 0 {
+		word := t.NextBits(uint(len(tail)) << 3)
+		for i := range tail {
+			tail[i] = byte(word >> (uint(i) << 3))
+		}
+	}
+	if t.msbFirst {
+		reverseBits(buf)
+	}
+}
+
+// Discard advances the key stream by n bits without returning them.
+func (t *Trivium) Discard(n uint64) {
+	for ; n >= wordSize; n -= wordSize {
+		t.NextUint64()
+	}
+	if n > 0 {
+		t.NextBits(uint(n))
+	}
+}
+
+// AppendKeyStream appends the next n bytes of key stream to dst and returns the extended slice.
+// Passing a dst with enough spare capacity avoids any allocation.
+func (t *Trivium) AppendKeyStream(dst []byte, n int) []byte {
+	dst = slices.Grow(dst, n)
+	buf := dst[len(dst) : len(dst)+n]
+	t.KeyStream(buf)
+	return dst[:len(dst)+n]
+}
+
+// ReadKeyStream fills p with the next len(p) bytes of key stream like KeyStream, with the
+// signature of io.Reader.  It always returns len(p) and a nil error.
+func (t *Trivium) ReadKeyStream(p []byte) (n int, err error) {
+	t.KeyStream(p)
+	return len(p), nil
+}
+
 // NextByte returns the next byte of key stream with the MSB as the last bit produced.
 // the first byte produced will have bits [76543210] of the keystream
 func (t *Trivium) NextByte() byte {
+	if t.msbFirst {
+		return bits.Reverse8(byte(t.NextBits(8)))
+	}
 	return byte(t.NextBits(8))
 }
 
 // NextBytes returns the next 1 to 8 bytes of key stream with the MSB as the last bit produced.
 // the first byte produced will have bits [76543210] of the keystream
+// NextBytes allocates the returned slice, AppendKeyStream and KeyStream fill a caller's buffer.
 func (t *Trivium) NextBytes(n uint) []byte {
 	output := make([]byte, n)
 	word := t.NextBits(n << 3)
 	for i := uint(0); i < n; i++ {
 		output[i] = byte(word >> (i << 3))
 	}
+	if t.msbFirst {
+		reverseBits(output)
+	}
 
 	return output
 }
 
-// reverseByte reverses the bits in byte
-func reverseByte(b byte) byte {
-	return ((b & 0x1) << 7) | ((b & 0x80) >> 7) |
-		((b & 0x2) << 5) | ((b & 0x40) >> 5) |
-		((b & 0x4) << 3) | ((b & 0x20) >> 3) |
-		((b & 0x8) << 1) | ((b & 0x10) >> 1)
+// reverseBits reverses the bits in every byte of b
+func reverseBits(b []byte) {
+	for i := range b {
+		b[i] = bits.Reverse8(b[i])
+	}
 }
@@ -1,13 +1,12 @@
 package trivium
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"os"
-	"reflect"
-	"regexp"
-	"strconv"
 	"testing"
+
+	"github.com/bmkessler/trivium/testvectors"
 )
 
 func ExampleNewTrivium() {
@@ -43,117 +42,61 @@ func ExampleNewTrivium() {
 // the vectors are big-endian, i.e. most significant byte of key and iv is on the left [9][8]...[1][0]
 // but the bit order is flipped, i.e. 0x80 should be loaded as 0x01 etc.
 // [72,73,74,75,76,77,78,79][64,65,66,67,68,69,70,71]...[8,9,10,11,12,13,14,15][0,1,2,3,4,5,6,7]
+// this is the ESTREAM convention of ParseKey and ParseIV
 
 const TestVectorFile8080 = "trivium-80.80.test-vectors"
 
-const (
-	testvectorLineLength  = 16 // 16 bytes per line in the test vectors
-	testvectorTotalLength = 64 // 64 bytes per block of test vector
-)
-
-var (
-	keyRe    = regexp.MustCompile(`key = ([0-9A-F]{20})`)
-	ivRe     = regexp.MustCompile(`IV = ([0-9A-F]{20})`)
-	streamRe = regexp.MustCompile(`stream\[([0-9]+)..[0-9]+\] = ([0-9A-F]{32})`)
-	dataRe   = regexp.MustCompile(`([0-9A-F]{32})`)
-	digestRe = regexp.MustCompile(`digest = ([0-9A-F]{20})`)
-)
-
 func TestTriviumTestVectors(t *testing.T) {
 	file, err := os.Open(TestVectorFile8080)
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer file.Close()
-	var key, iv [KeyLength]byte
-	var startingByte uint64
-	var tv []byte
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		text := scanner.Text()
-		if keyRe.MatchString(text) {
-			key = [KeyLength]byte{}
-			matches := keyRe.FindStringSubmatch(text)
-			for i := 0; i < KeyLength; i++ {
-				val, err := strconv.ParseUint(matches[1][2*i:2*i+2], 16, 8)
-				if err != nil {
-					t.Error(err)
-				}
-				key[KeyLength-1-i] = byte(val) // the highest key bits are on the left
-			}
-		} else if ivRe.MatchString(text) {
-			iv = [KeyLength]byte{}
-			matches := ivRe.FindStringSubmatch(text)
-			for i := 0; i < KeyLength; i++ {
-				val, err := strconv.ParseUint(matches[1][2*i:2*i+2], 16, 8)
-				if err != nil {
-					t.Error(err)
-				}
-				iv[KeyLength-1-i] = byte(val) // the highest iv bits are on the left
-			}
-		} else if streamRe.MatchString(text) {
-			tv = []byte{}
-			matches := streamRe.FindStringSubmatch(text)
-			startingByte, err = strconv.ParseUint(matches[1], 10, 64)
-			if err != nil {
-				t.Error(err)
-			}
-			for i := 0; i < testvectorLineLength; i++ {
-				val, err := strconv.ParseUint(matches[2][2*i:2*i+2], 16, 8)
-				if err != nil {
-					t.Error(err)
-				}
-				tv = append(tv, byte(val))
-			}
-		} else if dataRe.MatchString(text) {
-			matches := dataRe.FindStringSubmatch(text)
-			for i := 0; i < testvectorLineLength; i++ {
-				val, err := strconv.ParseUint(matches[1][2*i:2*i+2], 16, 8)
-				if err != nil {
-					t.Error(err)
-				}
-				tv = append(tv, byte(val))
-			}
-		} else if digestRe.MatchString(text) {
-			tv = []byte{} // ignore the digest and only test the key stream
-		}
-		if len(tv) == 64 {
-			var revKey, revIV [KeyLength]byte
-			for i := 0; i < KeyLength; i++ { // the bit order needs to be reversed to correspond to test vectors
-				revKey[i] = reverseByte(key[i])
-				revIV[i] = reverseByte(iv[i])
-			}
-			trivium := NewTrivium(revKey, revIV)
-			for i := uint64(0); i < startingByte; i++ {
-				trivium.NextByte()
-			}
-			got := []byte{}
-			for i := uint64(0); i < testvectorTotalLength; i++ {
-				testByte = trivium.NextByte()
-				got = append(got, testByte)
-
-			}
-
-			if reflect.DeepEqual(got, tv) != true {
-				t.Errorf("key:   %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", key[9], key[8], key[7], key[6], key[5], key[4], key[3], key[2], key[1], key[0])
-				t.Errorf("iv:    %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", iv[9], iv[8], iv[7], iv[6], iv[5], iv[4], iv[3], iv[2], iv[1], iv[0])
-				t.Errorf("test vector starts at byte: %d", startingByte)
-				t.Errorf("want:  %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", tv[0], tv[1], tv[2], tv[3], tv[4], tv[5], tv[6], tv[7], tv[8], tv[9], tv[10], tv[11], tv[12], tv[13], tv[14], tv[15])
-				t.Errorf("got:   %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", got[0], got[1], got[2], got[3], got[4], got[5], got[6], got[7], got[8], got[9], got[10], got[11], got[12], got[13], got[14], got[15])
-				t.Errorf("want:  %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", tv[16], tv[17], tv[18], tv[19], tv[20], tv[21], tv[22], tv[23], tv[24], tv[25], tv[26], tv[27], tv[28], tv[29], tv[30], tv[31])
-				t.Errorf("got:   %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", got[16], got[17], got[18], got[19], got[20], got[21], got[22], got[23], got[24], got[25], got[26], got[27], got[28], got[29], got[30], got[31])
-				t.Errorf("want:  %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", tv[32], tv[33], tv[34], tv[35], tv[36], tv[37], tv[38], tv[39], tv[40], tv[41], tv[42], tv[43], tv[44], tv[45], tv[46], tv[47])
-				t.Errorf("got:   %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", got[32], got[33], got[34], got[35], got[36], got[37], got[38], got[39], got[40], got[41], got[42], got[43], got[44], got[45], got[46], got[47])
-				t.Errorf("want:  %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", tv[48], tv[49], tv[50], tv[51], tv[52], tv[53], tv[54], tv[55], tv[56], tv[57], tv[58], tv[59], tv[60], tv[61], tv[62], tv[63])
-				t.Errorf("got:   %02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X%02X\n", got[48], got[49], got[50], got[51], got[52], got[53], got[54], got[55], got[56], got[57], got[58], got[59], got[60], got[61], got[62], got[63])
-			}
+	vectors, err := testvectors.Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors.Vectors) != 84 {
+		t.Errorf("parsed %d vectors, want 84", len(vectors.Vectors))
+	}
+	for _, result := range vectors.Verify(keyStreamESTREAM) {
+		if !result.Pass() {
+			t.Error(result)
 		}
 	}
+}
 
-	if err := scanner.Err(); err != nil {
+func TestGenerateTestVectors(t *testing.T) {
+	want, err := os.ReadFile(TestVectorFile8080)
+	if err != nil {
 		t.Fatal(err)
 	}
+	f := testvectors.ESTREAMSets("TRIVIUM", "___H3", 80, 80)
+	if err := f.Generate(keyStreamESTREAM); err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if err := f.Write(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("generated vectors differ from %s", TestVectorFile8080)
+	}
+}
+
+// keyStreamESTREAM is the testvectors.KeyStreamFunc of NewTrivium for keys and IVs written
+// in the ESTREAM convention.
+func keyStreamESTREAM(k, v, stream []byte) error {
+	key, err := KeyFromBytes(k, ESTREAM)
+	if err != nil {
+		return err
+	}
+	iv, err := IVFromBytes(v, ESTREAM)
+	if err != nil {
+		return err
+	}
+	NewTrivium(key, iv).KeyStream(stream)
+	return nil
 }
 
 func TestTriviumSWAR(t *testing.T) {
@@ -162,7 +105,7 @@ func TestTriviumSWAR(t *testing.T) {
 	var trivium = NewTrivium(key, IV)
 	var triviumSWAR = NewTrivium(key, IV)
 	var totalBitsToCompare uint = 4 * 288
-	var maxSWARwidth uint = 32
+	var maxSWARwidth uint = wordSize
 	for SWARwidth := uint(1); SWARwidth <= maxSWARwidth; SWARwidth++ {
 		for i := uint(0); i < totalBitsToCompare; {
 			SWARbits := triviumSWAR.NextBits(SWARwidth)
@@ -201,6 +144,86 @@ func TestTriviumBytes(t *testing.T) {
 	}
 }
 
+func TestTriviumUint64(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var IV = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	var trivium = NewTrivium(key, IV)
+	var triviumWord = NewTrivium(key, IV)
+	var totalWordsToCompare = 4 * 288
+	for i := 0; i < totalWordsToCompare; i++ {
+		word := triviumWord.NextUint64()
+		if i%3 == 0 { // interleave with the masked path to check they leave the same state
+			word = triviumWord.NextBits(wordSize)
+			trivium.NextBits(wordSize)
+		}
+		for j := uint(0); j < wordSize; j++ {
+			bit := trivium.NextBit()
+			if bit != (word>>j)&1 {
+				t.Fatalf("word %d bit %d doesn't match %d != %d", i, j, bit, (word>>j)&1)
+			}
+		}
+	}
+}
+
+func TestTriviumKeyStream(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var IV = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	var trivium = NewTrivium(key, IV)
+	var triviumStream = NewTrivium(key, IV)
+	for length := 0; length < 40; length++ {
+		buf := make([]byte, length)
+		triviumStream.KeyStream(buf)
+		for i := range buf {
+			if want := trivium.NextByte(); buf[i] != want {
+				t.Errorf("KeyStream of length %d at %d doesn't match %02X != %02X", length, i, buf[i], want)
+			}
+		}
+	}
+}
+
+func TestTriviumReset(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var IV = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	var trivium = NewTrivium([KeyLength]byte{}, [KeyLength]byte{})
+	trivium.KeyStream(make([]byte, 13))
+	trivium.Reset(key, IV)
+	if trivium.Count() != 0 {
+		t.Errorf("Reset count %d != 0", trivium.Count())
+	}
+	// the wide warm-up must match a warm-up one bit at a time from the loaded state
+	var bitwise = trivium.Clone()
+	for i := 0; i < 4*288; i++ {
+		bitwise.prevStep()
+	}
+	for i := 0; i < 4*288; i++ {
+		bitwise.NextBit()
+	}
+	if bitwise.NextUint64() != trivium.NextUint64() {
+		t.Errorf("Reset doesn't match a warm-up one bit at a time")
+	}
+}
+
+func TestTriviumAppendKeyStream(t *testing.T) {
+	var key = [10]byte{0x5F, 0xE5, 0x2A, 0x80, 0x75, 0xDA, 0x10, 0xAD, 0x46, 0xF0}
+	var IV = [10]byte{0xE3, 0x06, 0x9F, 0x49, 0xD4, 0x23, 0xBA, 0x6F, 0xF1, 0x14}
+	want := make([]byte, 100)
+	NewTrivium(key, IV).KeyStream(want)
+
+	trivium := NewTrivium(key, IV)
+	got := []byte("prefix")
+	got = trivium.AppendKeyStream(got, 37)
+	got = trivium.AppendKeyStream(got, 0)
+	buf := make([]byte, 63)
+	n, err := trivium.ReadKeyStream(buf)
+	got = append(got, buf...)
+	if err != nil || n != len(buf) {
+		t.Errorf("ReadKeyStream returned %d, %v", n, err)
+	}
+	if string(got[:6]) != "prefix" || !bytes.Equal(got[6:], want) {
+		t.Errorf("AppendKeyStream and ReadKeyStream don't match KeyStream")
+	}
+}
+
 var testBit uint64
 var testByte byte
 var testBytes []byte
@@ -246,3 +269,69 @@ func BenchmarkTriviumBytes(b *testing.B) {
 		testByte = 1
 	} // to avoid optimizing out the loop entirely
 }
+
+func BenchmarkTriviumUint64(b *testing.B) {
+	var key = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var IV = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var trivium = NewTrivium(key, IV)
+
+	b.SetBytes(wordSize >> 3)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		testBit = trivium.NextUint64()
+	}
+}
+
+func BenchmarkTriviumKeyStream(b *testing.B) {
+	var key = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var IV = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var trivium = NewTrivium(key, IV)
+	testBytes = make([]byte, 1024)
+
+	b.SetBytes(int64(len(testBytes)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trivium.KeyStream(testBytes)
+	}
+}
+
+func BenchmarkNewTrivium(b *testing.B) {
+	var key = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var IV = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var trivium *Trivium
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		IV[0] = byte(i)
+		trivium = NewTrivium(key, IV)
+	}
+	testBit = trivium.NextBit()
+}
+
+func BenchmarkTriviumReset(b *testing.B) {
+	var key = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var IV = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var trivium Trivium
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		IV[0] = byte(i)
+		trivium.Reset(key, IV)
+	}
+	testBit = trivium.NextBit()
+}
+
+func BenchmarkTriviumPacket(b *testing.B) {
+	var key = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var IV = [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	var trivium Trivium
+	packet := make([]byte, 64)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(packet)))
+	for i := 0; i < b.N; i++ {
+		IV[0] = byte(i)
+		trivium.Reset(key, IV)
+		trivium.XORKeyStream(packet, packet)
+	}
+}